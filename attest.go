@@ -3,6 +3,7 @@ package evervault
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/hex"
@@ -15,8 +16,6 @@ import (
 	"github.com/hf/nitrite"
 )
 
-const loadDocTimeout = 30 * time.Second
-
 // mapAttestationPCRs maps the attestation document's PCRs to a PCRs struct.
 func mapAttestationPCRs(attestationPCRs nitrite.Document) attestation.PCRs {
 	// We verify a subset of non zero PCRs
@@ -28,9 +27,26 @@ func mapAttestationPCRs(attestationPCRs nitrite.Document) attestation.PCRs {
 	return attestation.PCRs{PCR0: PCR0, PCR1: PCR1, PCR2: PCR2, PCR8: PCR8}
 }
 
+// docRequirements are the checks an attestation doc must pass beyond its PCRs.
+type docRequirements struct {
+	// roots verify the certificate the doc is signed with, the AWS Nitro Enclaves root is used if nil.
+	roots *x509.CertPool
+	// maxAge is the maximum age of the doc, zero disables the check.
+	maxAge time.Duration
+	// nonce is the challenge the doc must be bound to, nil disables the check.
+	nonce []byte
+}
+
 // attestCert attests the certificate against the expected PCRs.
-func attestCert(certificate *x509.Certificate, expectedPCRs []attestation.PCRs, attestationDoc []byte) (bool, error) {
-	res, err := nitrite.Verify(attestationDoc, nitrite.VerifyOptions{CurrentTime: time.Now()})
+func attestCert(
+	certificate *x509.Certificate,
+	expectedPCRs []attestation.PCRs,
+	attestationDoc []byte,
+	requirements docRequirements,
+) (bool, error) {
+	now := time.Now()
+
+	res, err := nitrite.Verify(attestationDoc, nitrite.VerifyOptions{Roots: requirements.roots, CurrentTime: now})
 	if err != nil {
 		return false, fmt.Errorf("unable to verify certificate %w", err)
 	}
@@ -39,6 +55,10 @@ func attestCert(certificate *x509.Certificate, expectedPCRs []attestation.PCRs,
 		return false, ErrUnVerifiedSignature
 	}
 
+	if err := checkDocRequirements(*res.Document, requirements, now); err != nil {
+		return false, err
+	}
+
 	if verified := verifyPCRs(expectedPCRs, *res.Document); !verified {
 		return verified, nil
 	}
@@ -52,6 +72,23 @@ func attestCert(certificate *x509.Certificate, expectedPCRs []attestation.PCRs,
 	return bytes.Equal(pubKeyBytes, res.Document.UserData), nil
 }
 
+// checkDocRequirements checks the freshness and nonce of a verified attestation document.
+func checkDocRequirements(doc nitrite.Document, requirements docRequirements, now time.Time) error {
+	if requirements.maxAge > 0 {
+		//nolint:gosec
+		issuedAt := time.UnixMilli(int64(doc.Timestamp))
+		if now.Sub(issuedAt) > requirements.maxAge {
+			return fmt.Errorf("%w: issued at %s", ErrStaleAttestationDoc, issuedAt)
+		}
+	}
+
+	if requirements.nonce != nil && !bytes.Equal(doc.Nonce, requirements.nonce) {
+		return ErrAttestationNonceMismatch
+	}
+
+	return nil
+}
+
 // verifyPCRs verifies the expected PCRs against the attestation document.
 func verifyPCRs(expectedPCRs []attestation.PCRs, attestationDocument nitrite.Document) bool {
 	attestationPCRs := mapAttestationPCRs(attestationDocument)
@@ -64,67 +101,166 @@ func verifyPCRs(expectedPCRs []attestation.PCRs, attestationDocument nitrite.Doc
 	return false
 }
 
-// filterEmptyPCRs removes empty PCR sets from the given slice.
-func filterEmptyPCRs(expectedPCRs []attestation.PCRs) []attestation.PCRs {
+// filterEmptyPCRs removes empty PCR sets from the given slice and validates the remaining sets.
+// ErrNoPCRs is returned if no PCR sets remain.
+func filterEmptyPCRs(expectedPCRs []attestation.PCRs) ([]attestation.PCRs, error) {
 	var ret []attestation.PCRs
 
 	for _, pcrs := range expectedPCRs {
-		if !pcrs.IsEmpty() {
-			ret = append(ret, pcrs)
+		if pcrs.IsEmpty() {
+			continue
+		}
+
+		if err := pcrs.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidPCRs, err)
 		}
+
+		ret = append(ret, pcrs)
+	}
+
+	if len(ret) == 0 {
+		return nil, ErrNoPCRs
 	}
 
-	return ret
+	return ret, nil
 }
 
-// dialTimeout specifies the timeout duration for dialing a remote host.
-var dialTimeout = 5 * time.Second
+// challengeNonceSize is the number of random bytes sent as a challenge when requesting an attestation doc.
+const challengeNonceSize = 32
 
 // createDial returns a custom dial function that performs attestation on the connection.
 func (c *Client) createDial(
 	tlsConfig *tls.Config,
 	cache *internalAttestation.Cache,
 	pcrManager internalAttestation.PCRManager,
+	options enclaveOptions,
 ) func(ctx context.Context, network, addr string) (net.Conn, error) {
-	return func(dialCtx context.Context, network, addr string) (net.Conn, error) {
+	tracer := c.tracer()
+
+	dial := func(dialCtx context.Context, network, addr string) (net.Conn, error) {
 		if network != "tcp" {
 			return nil, ErrUnsupportedNetworkType
 		}
 
+		connectCtx, cancel := withTimeout(dialCtx, options.dialTimeout)
+		defer cancel()
+
+		// PCRs can change whenever the provider is polled, so they are validated on every dial.
+		expectedPCRs, err := filterEmptyPCRs(*pcrManager.Get())
+		if err != nil {
+			return nil, err
+		}
+
 		// Create a TCP connection
-		conn, err := net.DialTimeout(network, addr, dialTimeout)
+		var dialer net.Dialer
+
+		conn, err := dialer.DialContext(connectCtx, network, addr)
 		if err != nil {
 			return nil, fmt.Errorf("error creating cage dial %w", err)
 		}
 
-		expectedPCRs := pcrManager.Get()
-
 		// Perform TLS handshake with custom configuration
 		tlsConn := tls.Client(conn, tlsConfig)
-		if err = tlsConn.Handshake(); err != nil {
+		if err = tlsConn.HandshakeContext(connectCtx); err != nil {
+			conn.Close()
 			return nil, fmt.Errorf("error connecting to cage %w", err)
 		}
 
 		cert := tlsConn.ConnectionState().PeerCertificates[0]
-		doc := cache.Get()
-
-		attestationDoc, err := attestCert(cert, *expectedPCRs, doc)
-		if err != nil {
-			loadCtx, cancel := context.WithTimeout(dialCtx, loadDocTimeout)
-			defer cancel()
 
-			cache.LoadDoc(loadCtx)
+		var attestationDoc bool
+		if options.challenge {
+			attestationDoc, err = attestChallenge(dialCtx, cert, expectedPCRs, cache, options)
+		} else {
+			attestationDoc, err = attestCached(dialCtx, cert, expectedPCRs, cache, options)
+		}
 
-			attestationDoc, err = attestCert(cert, *expectedPCRs, cache.Get())
-			if err != nil {
-				return nil, fmt.Errorf("error attesting Connection %w", err)
-			}
+		if err != nil {
+			tlsConn.Close()
+			return nil, err
 		}
 
 		if !attestationDoc {
+			tlsConn.Close()
 			return nil, ErrAttestionFailure
 		}
 
 		return tlsConn, nil
 	}
+
+	return func(dialCtx context.Context, network, addr string) (conn net.Conn, err error) {
+		ctx, span := tracer.Start(dialCtx, OperationEnclaveDial, map[string]string{"hostname": tlsConfig.ServerName})
+		defer func() { span.End(err) }()
+
+		return dial(ctx, network, addr)
+	}
+}
+
+// attestCached attests the certificate against the cached attestation doc, reloading the doc once if
+// it cannot be verified.
+func attestCached(
+	dialCtx context.Context,
+	cert *x509.Certificate,
+	expectedPCRs []attestation.PCRs,
+	cache *internalAttestation.Cache,
+	options enclaveOptions,
+) (bool, error) {
+	requirements := docRequirements{roots: options.attestationRoots, maxAge: options.maxDocAge}
+
+	attestationDoc, err := attestCert(cert, expectedPCRs, cache.Get(), requirements)
+	if err == nil {
+		return attestationDoc, nil
+	}
+
+	loadCtx, cancel := withTimeout(dialCtx, options.docTimeout)
+	defer cancel()
+
+	cache.LoadDoc(loadCtx)
+
+	attestationDoc, err = attestCert(cert, expectedPCRs, cache.Get(), requirements)
+	if err != nil {
+		return false, fmt.Errorf("error attesting Connection %w", err)
+	}
+
+	return attestationDoc, nil
+}
+
+// attestChallenge requests a fresh attestation doc bound to a random nonce and attests the certificate against it.
+func attestChallenge(
+	dialCtx context.Context,
+	cert *x509.Certificate,
+	expectedPCRs []attestation.PCRs,
+	cache *internalAttestation.Cache,
+	options enclaveOptions,
+) (bool, error) {
+	nonce := make([]byte, challengeNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return false, fmt.Errorf("error generating attestation nonce %w", err)
+	}
+
+	loadCtx, cancel := withTimeout(dialCtx, options.docTimeout)
+	defer cancel()
+
+	doc, err := cache.GetChallengeDoc(loadCtx, nonce)
+	if err != nil {
+		return false, fmt.Errorf("error attesting Connection %w", err)
+	}
+
+	requirements := docRequirements{roots: options.attestationRoots, maxAge: options.maxDocAge, nonce: nonce}
+
+	attestationDoc, err := attestCert(cert, expectedPCRs, doc, requirements)
+	if err != nil {
+		return false, fmt.Errorf("error attesting Connection %w", err)
+	}
+
+	return attestationDoc, nil
+}
+
+// withTimeout returns a context that is cancelled after the timeout, or only when ctx is if the timeout is not set.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
 }
@@ -1,39 +1,98 @@
 package attestation
 
-// prcEqual Checks if 2 PCR strings are not equal.
-func pcrNotEqual(p1, p2 string) bool {
-	return p1 != "" && p2 != "" && p1 != p2
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// pcrLength is the length of a hex encoded SHA-384 PCR measurement.
+const pcrLength = 96
+
+// ErrMalformedPCR is returned when a PCR is not a hex encoded SHA-384 measurement.
+var ErrMalformedPCR = errors.New("PCR must be a 96 character hex encoded SHA-384 measurement")
+
+// ErrWildcardPCR is returned when a PCR is left empty without opting in to wildcard matching.
+var ErrWildcardPCR = errors.New("PCR is empty and AllowWildcard is not set")
+
+// pcrMatches checks if 2 PCR strings are equal, ignoring case. Empty PCRs only match when wildcards are allowed.
+func pcrMatches(p1, p2 string, allowWildcard bool) bool {
+	if p1 == "" || p2 == "" {
+		return allowWildcard
+	}
+
+	return strings.EqualFold(p1, p2)
 }
 
 // PCRs struct for attesting a cage connection against.
 type PCRs struct {
 	PCR0, PCR1, PCR2, PCR8 string
+	// AllowWildcard opts in to treating empty PCRs as wildcards that match any measurement.
+	// Without it every PCR must be provided.
+	AllowWildcard bool
 }
 
-// Check if two PCRs are equal to each other.
+// Check if two PCRs are equal to each other. PCRs are compared case insensitively. A set with every PCR empty never
+// matches, even when wildcards are allowed.
 func (p *PCRs) Equal(pcrs PCRs) bool {
-	if pcrNotEqual(p.PCR0, pcrs.PCR0) {
+	if p.IsEmpty() || pcrs.IsEmpty() {
 		return false
 	}
 
-	if pcrNotEqual(p.PCR1, pcrs.PCR1) {
-		return false
-	}
+	allowWildcard := p.AllowWildcard || pcrs.AllowWildcard
 
-	if pcrNotEqual(p.PCR2, pcrs.PCR2) {
-		return false
+	return pcrMatches(p.PCR0, pcrs.PCR0, allowWildcard) &&
+		pcrMatches(p.PCR1, pcrs.PCR1, allowWildcard) &&
+		pcrMatches(p.PCR2, pcrs.PCR2, allowWildcard) &&
+		pcrMatches(p.PCR8, pcrs.PCR8, allowWildcard)
+}
+
+// IsEmpty checks if all PCRs in the struct are empty.
+func (p *PCRs) IsEmpty() bool {
+	return p.PCR0 == "" && p.PCR1 == "" && p.PCR2 == "" && p.PCR8 == ""
+}
+
+// Validate checks that every PCR is a 96 character hex encoded SHA-384 measurement.
+// Empty PCRs are rejected with ErrWildcardPCR unless AllowWildcard is set.
+func (p *PCRs) Validate() error {
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"PCR0", p.PCR0},
+		{"PCR1", p.PCR1},
+		{"PCR2", p.PCR2},
+		{"PCR8", p.PCR8},
 	}
 
-	if pcrNotEqual(p.PCR8, pcrs.PCR8) {
-		return false
+	for _, field := range fields {
+		if err := validatePCR(field.value, p.AllowWildcard); err != nil {
+			return fmt.Errorf("%s: %w", field.name, err)
+		}
 	}
 
-	return true
+	return nil
 }
 
-// IsEmpty checks if all PCRs in the struct are empty.
-func (p *PCRs) IsEmpty() bool {
-	return p.PCR0 == "" && p.PCR1 == "" && p.PCR2 == "" && p.PCR8 == ""
+func validatePCR(pcr string, allowWildcard bool) error {
+	if pcr == "" {
+		if allowWildcard {
+			return nil
+		}
+
+		return ErrWildcardPCR
+	}
+
+	if len(pcr) != pcrLength {
+		return ErrMalformedPCR
+	}
+
+	if _, err := hex.DecodeString(pcr); err != nil {
+		return ErrMalformedPCR
+	}
+
+	return nil
 }
 
 func BuildStaticPcrProvider(pcrs []PCRs) func() ([]PCRs, error) {
@@ -0,0 +1,65 @@
+//go:build unit_test
+// +build unit_test
+
+package attestation_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/evervault/evervault-go/attestation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPCRsValidate(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	valid := attestation.PCRs{PCR0: pcr0, PCR1: pcr1, PCR2: pcr2, PCR8: strings.ToUpper(pcr8)}
+	assert.Nil(valid.Validate())
+
+	tooShort := attestation.PCRs{PCR0: pcr0[:64], PCR1: pcr1, PCR2: pcr2, PCR8: pcr8}
+	assert.ErrorIs(tooShort.Validate(), attestation.ErrMalformedPCR)
+
+	notHex := attestation.PCRs{PCR0: pcr0, PCR1: pcr1, PCR2: pcr2, PCR8: "INVALID" + pcr8[7:]}
+	assert.ErrorIs(notHex.Validate(), attestation.ErrMalformedPCR)
+
+	partial := attestation.PCRs{PCR8: pcr8}
+	assert.ErrorIs(partial.Validate(), attestation.ErrWildcardPCR)
+
+	partial.AllowWildcard = true
+	assert.Nil(partial.Validate())
+}
+
+func TestPCRsEqualIgnoresCase(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	expected := attestation.PCRs{PCR0: strings.ToUpper(pcr0), PCR1: pcr1, PCR2: pcr2, PCR8: pcr8}
+	actual := attestation.PCRs{PCR0: pcr0, PCR1: pcr1, PCR2: pcr2, PCR8: pcr8}
+
+	assert.True(expected.Equal(actual))
+
+	actual.PCR8 = pcr0
+	assert.False(expected.Equal(actual))
+}
+
+func TestPCRsEqualRequiresWildcardOptIn(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	actual := attestation.PCRs{PCR0: pcr0, PCR1: pcr1, PCR2: pcr2, PCR8: pcr8}
+
+	expected := attestation.PCRs{PCR8: pcr8}
+	assert.False(expected.Equal(actual))
+
+	expected.AllowWildcard = true
+	assert.True(expected.Equal(actual))
+
+	empty := attestation.PCRs{AllowWildcard: true}
+	assert.False(empty.Equal(actual))
+	assert.False(actual.Equal(empty))
+}
@@ -0,0 +1,241 @@
+package attestation
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoMeasurements is returned when a PCR source does not contain any PCR measurements.
+var ErrNoMeasurements = errors.New("no PCR measurements found")
+
+// unsignedPCR8 is the PCR8 of an enclave running an EIF that was not signed, which nitro-cli leaves out of the
+// measurements it prints.
+var unsignedPCR8 = strings.Repeat("0", pcrLength)
+
+// measurements mirrors the PCR fields written by `ev enclave build` and `nitro-cli describe-eif`.
+type measurements struct {
+	PCR0 string `json:"PCR0"`
+	PCR1 string `json:"PCR1"`
+	PCR2 string `json:"PCR2"`
+	PCR8 string `json:"PCR8"`
+}
+
+// toPCRs returns the measured PCRs, with the PCR8 of an unsigned EIF if PCR8 was left out.
+func (m measurements) toPCRs() PCRs {
+	pcrs := PCRs{PCR0: m.PCR0, PCR1: m.PCR1, PCR2: m.PCR2, PCR8: m.PCR8}
+	if pcrs.PCR8 == "" && !pcrs.IsEmpty() {
+		pcrs.PCR8 = unsignedPCR8
+	}
+
+	return pcrs
+}
+
+// buildOutput is the JSON document emitted by enclave builds. The measurements are either at the top
+// level or nested under Measurements (EIF measurement JSON) or attestation (ev enclave build).
+//
+//nolint:tagliatelle
+type buildOutput struct {
+	measurements
+	Measurements *measurements `json:"Measurements"`
+	Attestation  *measurements `json:"attestation"`
+}
+
+func (b buildOutput) toPCRs() PCRs {
+	if b.Measurements != nil {
+		return b.Measurements.toPCRs()
+	}
+
+	if b.Attestation != nil {
+		return b.Attestation.toPCRs()
+	}
+
+	return b.measurements.toPCRs()
+}
+
+// ParsePCRs parses PCRs from the output of an enclave build. The following formats are supported:
+//   - the JSON printed by `ev enclave build` or `nitro-cli build-enclave` / `nitro-cli describe-eif`, where PCR8
+//     is all zeros if the EIF was not signed
+//   - a JSON array of such documents, to attest against multiple builds
+//   - the [attestation] section written to enclave.toml by `ev enclave build`
+func ParsePCRs(data []byte) ([]PCRs, error) {
+	data = bytes.TrimSpace(data)
+
+	var (
+		pcrs []PCRs
+		err  error
+	)
+
+	switch {
+	case len(data) == 0:
+		return nil, ErrNoMeasurements
+	case data[0] == '[':
+		pcrs, err = parseJSONArray(data)
+	case data[0] == '{':
+		pcrs, err = parseJSONObject(data)
+	default:
+		pcrs, err = parseTOMLAttestation(data)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range pcrs {
+		if !p.IsEmpty() {
+			return pcrs, nil
+		}
+	}
+
+	return nil, ErrNoMeasurements
+}
+
+func parseJSONObject(data []byte) ([]PCRs, error) {
+	var output buildOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("error parsing PCR JSON %w", err)
+	}
+
+	return []PCRs{output.toPCRs()}, nil
+}
+
+func parseJSONArray(data []byte) ([]PCRs, error) {
+	var outputs []buildOutput
+	if err := json.Unmarshal(data, &outputs); err != nil {
+		return nil, fmt.Errorf("error parsing PCR JSON %w", err)
+	}
+
+	pcrs := make([]PCRs, 0, len(outputs))
+	for _, output := range outputs {
+		pcrs = append(pcrs, output.toPCRs())
+	}
+
+	return pcrs, nil
+}
+
+// parseTOMLAttestation reads the PCR keys from the [attestation] table of an enclave.toml.
+// Only the flat `key = "value"` form written by the Evervault CLI is supported.
+func parseTOMLAttestation(data []byte) ([]PCRs, error) {
+	var (
+		pcrs    PCRs
+		inTable bool
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			inTable = line == "[attestation]"
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !inTable || !found {
+			continue
+		}
+
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+
+		switch strings.TrimSpace(key) {
+		case "PCR0":
+			pcrs.PCR0 = value
+		case "PCR1":
+			pcrs.PCR1 = value
+		case "PCR2":
+			pcrs.PCR2 = value
+		case "PCR8":
+			pcrs.PCR8 = value
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading enclave.toml %w", err)
+	}
+
+	return []PCRs{pcrs}, nil
+}
+
+// FileProvider returns a PCR provider that reads the PCRs from a file on every call. The file can be the
+// output of `ev enclave build`, an enclave.toml, EIF measurement JSON or a mounted secret in any of the
+// formats supported by ParsePCRs.
+//
+//	enclaveClient, err := evClient.EnclaveClientWithProvider(enclaveURL, attestation.FileProvider("enclave.toml"))
+func FileProvider(path string) func() ([]PCRs, error) {
+	return func() ([]PCRs, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading PCR file %w", err)
+		}
+
+		return ParsePCRs(data)
+	}
+}
+
+// EnvProvider returns a PCR provider that reads the PCRs from an environment variable. The variable
+// can hold any of the formats supported by ParsePCRs.
+//
+//	enclaveClient, err := evClient.EnclaveClientWithProvider(enclaveURL, attestation.EnvProvider("ENCLAVE_PCRS"))
+func EnvProvider(name string) func() ([]PCRs, error) {
+	return func() ([]PCRs, error) {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s is not set: %w", name, ErrNoMeasurements)
+		}
+
+		return ParsePCRs([]byte(value))
+	}
+}
+
+// WatchFileProvider returns a PCR provider that only re-reads the file when its size or modification
+// time changes, so it can be polled frequently to pick up new builds as they are deployed.
+// If the file can no longer be read or parsed an error is returned rather than the stale PCRs.
+func WatchFileProvider(path string) func() ([]PCRs, error) {
+	watcher := &fileWatcher{path: path}
+
+	return watcher.get
+}
+
+type fileWatcher struct {
+	path    string
+	mutex   sync.Mutex
+	modTime time.Time
+	size    int64
+	pcrs    []PCRs
+}
+
+func (w *fileWatcher) get() ([]PCRs, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	info, err := os.Stat(w.path)
+	if err != nil {
+		w.pcrs = nil
+		return nil, fmt.Errorf("error reading PCR file %w", err)
+	}
+
+	if w.pcrs != nil && info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return w.pcrs, nil
+	}
+
+	pcrs, err := FileProvider(w.path)()
+	if err != nil {
+		w.pcrs = nil
+		return nil, err
+	}
+
+	w.pcrs = pcrs
+	w.modTime = info.ModTime()
+	w.size = info.Size()
+
+	return pcrs, nil
+}
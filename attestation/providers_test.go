@@ -0,0 +1,176 @@
+//go:build unit_test
+// +build unit_test
+
+package attestation_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evervault/evervault-go/attestation"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	pcr0 = strings.Repeat("a", 96)
+	pcr1 = strings.Repeat("b", 96)
+	pcr2 = strings.Repeat("c", 96)
+	pcr8 = strings.Repeat("d", 96)
+
+	unsignedPCR8 = strings.Repeat("0", 96)
+)
+
+func TestParsePCRsBuildOutput(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	output := `{"PCR0": "` + pcr0 + `", "PCR1": "` + pcr1 + `", "PCR2": "` + pcr2 + `", "PCR8": "` + pcr8 + `"}`
+
+	pcrs, err := attestation.ParsePCRs([]byte(output))
+	assert.Nil(err)
+	assert.Equal([]attestation.PCRs{{PCR0: pcr0, PCR1: pcr1, PCR2: pcr2, PCR8: pcr8}}, pcrs)
+}
+
+func TestParsePCRsEIFMeasurements(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	output := `{
+		"Measurements": {
+			"HashAlgorithm": "Sha384 { ... }",
+			"PCR0": "` + pcr0 + `",
+			"PCR1": "` + pcr1 + `",
+			"PCR2": "` + pcr2 + `"
+		}
+	}`
+
+	pcrs, err := attestation.ParsePCRs([]byte(output))
+	assert.Nil(err)
+	assert.Equal([]attestation.PCRs{{PCR0: pcr0, PCR1: pcr1, PCR2: pcr2, PCR8: unsignedPCR8}}, pcrs)
+	assert.Nil(pcrs[0].Validate())
+}
+
+func TestParsePCRsUnsignedEIF(t *testing.T) {
+	t.Parallel()
+
+	measured := `{"PCR0": "` + pcr0 + `", "PCR1": "` + pcr1 + `", "PCR2": "` + pcr2 + `"}`
+
+	for name, output := range map[string]string{
+		"top level":    measured,
+		"Measurements": `{"Measurements": ` + measured + `}`,
+		"attestation":  `{"attestation": ` + measured + `}`,
+	} {
+		pcrs, err := attestation.ParsePCRs([]byte(output))
+		assert.Nil(t, err, name)
+		assert.Equal(t, []attestation.PCRs{{PCR0: pcr0, PCR1: pcr1, PCR2: pcr2, PCR8: unsignedPCR8}}, pcrs, name)
+	}
+}
+
+func TestParsePCRsMultipleBuilds(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	output := `[{"PCR0": "` + pcr0 + `"}, {"Measurements": {"PCR0": "` + pcr1 + `"}}]`
+
+	pcrs, err := attestation.ParsePCRs([]byte(output))
+	assert.Nil(err)
+	assert.Equal([]attestation.PCRs{{PCR0: pcr0, PCR8: unsignedPCR8}, {PCR0: pcr1, PCR8: unsignedPCR8}}, pcrs)
+}
+
+func TestParsePCRsEnclaveToml(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	output := `version = 1
+name = "hello-enclave"
+
+[attestation]
+HashAlgorithm = "Sha384 { ... }"
+PCR0 = "` + pcr0 + `"
+PCR1 = "` + pcr1 + `"
+PCR2 = "` + pcr2 + `"
+PCR8 = "` + pcr8 + `"
+
+[signing]
+certPath = "./cert.pem"
+`
+
+	pcrs, err := attestation.ParsePCRs([]byte(output))
+	assert.Nil(err)
+	assert.Equal([]attestation.PCRs{{PCR0: pcr0, PCR1: pcr1, PCR2: pcr2, PCR8: pcr8}}, pcrs)
+}
+
+func TestParsePCRsNoMeasurements(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	_, err := attestation.ParsePCRs([]byte(`{"digest": "SHA384"}`))
+	assert.ErrorIs(err, attestation.ErrNoMeasurements)
+
+	_, err = attestation.ParsePCRs([]byte(""))
+	assert.ErrorIs(err, attestation.ErrNoMeasurements)
+}
+
+func TestFileProvider(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "pcrs.json")
+	assert.Nil(os.WriteFile(path, []byte(`{"PCR8": "`+pcr8+`"}`), 0o600))
+
+	pcrs, err := attestation.FileProvider(path)()
+	assert.Nil(err)
+	assert.Equal([]attestation.PCRs{{PCR8: pcr8}}, pcrs)
+
+	_, err = attestation.FileProvider(filepath.Join(t.TempDir(), "missing.json"))()
+	assert.NotNil(err)
+}
+
+func TestEnvProvider(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("TEST_ENCLAVE_PCRS", `{"PCR0": "`+pcr0+`"}`)
+
+	pcrs, err := attestation.EnvProvider("TEST_ENCLAVE_PCRS")()
+	assert.Nil(err)
+	assert.Equal([]attestation.PCRs{{PCR0: pcr0, PCR8: unsignedPCR8}}, pcrs)
+
+	_, err = attestation.EnvProvider("TEST_ENCLAVE_PCRS_UNSET")()
+	assert.ErrorIs(err, attestation.ErrNoMeasurements)
+}
+
+func TestWatchFileProviderPicksUpChanges(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "pcrs.json")
+	assert.Nil(os.WriteFile(path, []byte(`{"PCR0": "`+pcr0+`"}`), 0o600))
+
+	provider := attestation.WatchFileProvider(path)
+
+	pcrs, err := provider()
+	assert.Nil(err)
+	assert.Equal([]attestation.PCRs{{PCR0: pcr0, PCR8: unsignedPCR8}}, pcrs)
+
+	assert.Nil(os.WriteFile(path, []byte(`{"PCR0": "`+pcr1+`"}`), 0o600))
+	assert.Nil(os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+
+	pcrs, err = provider()
+	assert.Nil(err)
+	assert.Equal([]attestation.PCRs{{PCR0: pcr1, PCR8: unsignedPCR8}}, pcrs)
+
+	assert.Nil(os.Remove(path))
+
+	_, err = provider()
+	assert.NotNil(err)
+}
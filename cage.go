@@ -1,11 +1,12 @@
 package evervault
 
 import (
-	"crypto/tls"
 	"net/http"
 
 	"github.com/evervault/evervault-go/attestation"
 	internalAttestation "github.com/evervault/evervault-go/internal/attestation"
+	"github.com/evervault/evervault-go/internal/logging"
+	"github.com/evervault/evervault-go/internal/telemetry"
 )
 
 // Will return a http.Client that is connected to a specified cage hostname with a fully attested client.
@@ -82,7 +83,9 @@ func (c *Client) CagesClient(cageHostname string, pcrs []attestation.PCRs) (*htt
 func (c *Client) CagesClientWithProvider(cageHostname string,
 	pcrsProvider func() ([]attestation.PCRs, error),
 ) (*http.Client, error) {
-	pcrManager := internalAttestation.NewPollingPCRManager(c.Config.CagesPollingInterval, pcrsProvider)
+	pcrManager := internalAttestation.NewPollingPCRManager(c.Config.CagesPollingInterval, pcrsProvider,
+		logging.With(c.logger(), "hostname", cageHostname),
+		telemetry.WithAttributes(c.tracer(), map[string]string{"hostname": cageHostname}))
 
 	cagesClient, err := c.createCagesClient(pcrManager, cageHostname)
 	if err != nil {
@@ -95,36 +98,15 @@ func (c *Client) CagesClientWithProvider(cageHostname string,
 func (c *Client) createCagesClient(pcrManager internalAttestation.PCRManager,
 	cageHostname string,
 ) (*http.Client, error) {
-	expectedPCRs := pcrManager.Get()
-
-	if len(filterEmptyPCRs(*expectedPCRs)) == 0 {
-		return nil, ErrNoPCRs
+	if c.isClosed() {
+		pcrManager.StopPolling()
+		return nil, ErrClientClosed
 	}
 
-	cache, err := internalAttestation.NewAttestationCache(cageHostname, c.Config.CagesPollingInterval)
+	session, err := c.openSession(cageHostname, pcrManager, c.Config.CagesPollingInterval, c.buildEnclaveOptions(nil))
 	if err != nil {
 		return nil, err
 	}
 
-	cagesClient := c.cagesClient(cageHostname, cache, pcrManager)
-
-	return cagesClient, nil
-}
-
-// cagesClient returns an HTTP client for connecting to the cage.
-func (c *Client) cagesClient(cageHostname string,
-	cache *internalAttestation.Cache, provider internalAttestation.PCRManager,
-) *http.Client {
-	tlsConfig := &tls.Config{
-		InsecureSkipVerify: false,
-		MinVersion:         tls.VersionTLS12,
-		ServerName:         cageHostname,
-	}
-
-	transport := &http.Transport{
-		DisableKeepAlives: true,
-		DialTLSContext:    c.createDial(tlsConfig, cache, provider),
-	}
-
-	return &http.Client{Transport: transport}
+	return session.HTTPClient(), nil
 }
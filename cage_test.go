@@ -135,7 +135,8 @@ func TestCagePartialPCR(t *testing.T) {
 	}
 
 	expectedPCRs := attestation.PCRs{
-		PCR8: "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		PCR8:          "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		AllowWildcard: true,
 	}
 
 	cageClient, err := testClient.CagesClient(cage, []attestation.PCRs{expectedPCRs})
@@ -177,8 +178,9 @@ func TestCagePartialPCR(t *testing.T) {
 func GetPCRData() ([]attestation.PCRs, error) {
 	var pcrs []attestation.PCRs
 	expectedPCRs := attestation.PCRs{
-		PCR0: "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
-		PCR8: "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		PCR0:          "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		PCR8:          "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		AllowWildcard: true,
 	}
 	pcrs = append(pcrs, expectedPCRs)
 	return pcrs, nil
@@ -187,8 +189,9 @@ func GetPCRData() ([]attestation.PCRs, error) {
 func GetInvalidPCRData() ([]attestation.PCRs, error) {
 	var pcrs []attestation.PCRs
 	expectedPCRs := attestation.PCRs{
-		PCR0: "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
-		PCR8: "INVALID00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		PCR0:          "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		PCR8:          "111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111",
+		AllowWildcard: true,
 	}
 	pcrs = append(pcrs, expectedPCRs)
 	return pcrs, nil
@@ -282,8 +285,9 @@ func TestCageFailsOnPartialIncorrectPCR(t *testing.T) {
 	}
 
 	expectedPCRs := attestation.PCRs{
-		PCR0: "111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111",
-		PCR8: "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		PCR0:          "111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111",
+		PCR8:          "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		AllowWildcard: true,
 	}
 
 	cageClient, err := testClient.CagesClient(cage, []attestation.PCRs{expectedPCRs})
@@ -5,9 +5,18 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/evervault/evervault-go/internal/crypto"
+	"github.com/evervault/evervault-go/internal/logging"
 )
 
 // Evervault Client.
@@ -17,12 +26,22 @@ import (
 //   - Create Outbound relay client
 //   - Create Cage clients
 //   - run evervault Functions.
+//
+// Enclave and Cage clients poll for attestation docs and PCRs in the background. Call Close to stop polling once
+// the Client is no longer needed.
 type Client struct {
 	Config                    Config
 	appUUID                   string
 	apiKey                    string
 	p256PublicKeyUncompressed []byte
 	p256PublicKeyCompressed   []byte
+	mutex                     sync.Mutex
+	closed                    bool
+	sessions                  map[*EnclaveSession]struct{}
+	token                     string
+	caMutex                   sync.Mutex
+	caCert                    []byte
+	caFetchedAt               time.Time
 }
 
 type KeysResponse struct {
@@ -40,12 +59,14 @@ type clientRequest struct {
 	appUUID      string
 	apiKey       string
 	useBasicAuth bool
+	token        string
 }
 
 type clientResponse struct {
 	body        []byte
 	contentType string
 	statusCode  int
+	header      http.Header
 }
 
 type TokenResponse struct {
@@ -54,6 +75,13 @@ type TokenResponse struct {
 }
 
 func (c *Client) initClient() error {
+	if len(c.Config.AppPublicKey) > 0 {
+		c.p256PublicKeyUncompressed = c.Config.AppPublicKey
+		c.p256PublicKeyCompressed = crypto.CompressPublicKey(c.Config.AppPublicKey)
+
+		return nil
+	}
+
 	keysResponse, err := c.getPublicKey()
 	if err != nil {
 		return err
@@ -75,19 +103,71 @@ func (c *Client) initClient() error {
 	return nil
 }
 
-func (c *Client) getPublicKey() (KeysResponse, error) {
-	publicKeyURL := c.Config.EvAPIURL + "/cages/key"
+// Close stops the background polling of every Enclave and Cage client created by the Client.
+// Clients created before Close continue to attest connections against the last PCRs and attestation docs
+// retrieved. New Enclave clients cannot be created once the Client is closed.
+func (c *Client) Close() error {
+	c.mutex.Lock()
+	c.closed = true
+	sessions := c.sessions
+	c.sessions = nil
+	c.mutex.Unlock()
+
+	for session := range sessions {
+		session.stop()
+	}
+
+	return nil
+}
 
-	response, err := c.makeRequest(publicKeyURL, http.MethodGet, nil, false)
+// logger returns the configured Logger, falling back to the standard log package.
+func (c *Client) logger() logging.Logger {
+	return logging.OrDefault(c.Config.Logger)
+}
 
-	if response.statusCode != http.StatusOK {
-		return KeysResponse{}, APIError{Message: "error making HTTP request"}
+func (c *Client) isClosed() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.closed
+}
+
+func (c *Client) trackSession(session *EnclaveSession) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return ErrClientClosed
+	}
+
+	if c.sessions == nil {
+		c.sessions = make(map[*EnclaveSession]struct{})
 	}
 
+	c.sessions[session] = struct{}{}
+
+	return nil
+}
+
+func (c *Client) untrackSession(session *EnclaveSession) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.sessions, session)
+}
+
+func (c *Client) getPublicKey() (KeysResponse, error) {
+	publicKeyURL := c.Config.EvAPIURL + "/cages/key"
+
+	response, err := c.makeRequest(context.Background(), publicKeyURL, http.MethodGet, nil, false)
 	if err != nil {
 		return KeysResponse{}, err
 	}
 
+	if response.statusCode != http.StatusOK {
+		return KeysResponse{}, extractAPIError(response)
+	}
+
 	res := KeysResponse{}
 	if err := json.Unmarshal(response.body, &res); err != nil {
 		return KeysResponse{}, fmt.Errorf("error parsing JSON response %w", err)
@@ -96,7 +176,10 @@ func (c *Client) getPublicKey() (KeysResponse, error) {
 	return res, nil
 }
 
-func (c *Client) decrypt(encryptedData string) (any, error) {
+func (c *Client) decrypt(encryptedData any) (result any, err error) {
+	ctx, span := c.tracer().Start(context.Background(), OperationDecrypt, nil)
+	defer func() { span.End(err) }()
+
 	pBytes, err := json.Marshal(encryptedData)
 	if err != nil {
 		return nil, fmt.Errorf("error marshalling payload to json %w", err)
@@ -104,16 +187,15 @@ func (c *Client) decrypt(encryptedData string) (any, error) {
 
 	decryptURL := c.Config.EvAPIURL + "/decrypt"
 
-	response, err := c.makeRequest(decryptURL, http.MethodPost, pBytes, true)
-
-	if response.statusCode != http.StatusOK {
-		return TokenResponse{}, ExtractAPIError(response.body)
-	}
-
+	response, err := c.makeRequest(ctx, decryptURL, http.MethodPost, pBytes, true)
 	if err != nil {
 		return nil, err
 	}
 
+	if response.statusCode != http.StatusOK {
+		return nil, extractAPIError(response)
+	}
+
 	var res any
 	if response.contentType == "application/json" {
 		if err := json.Unmarshal(response.body, &res); err != nil {
@@ -142,16 +224,15 @@ func (c *Client) createToken(action string, payload any, expiry int64) (TokenRes
 
 	tokenURL := c.Config.EvAPIURL + "/client-side-tokens"
 
-	response, err := c.makeRequest(tokenURL, http.MethodPost, bodyBytes, false)
-
-	if response.statusCode != http.StatusOK {
-		return TokenResponse{}, ExtractAPIError(response.body)
-	}
-
+	response, err := c.makeRequest(context.Background(), tokenURL, http.MethodPost, bodyBytes, false)
 	if err != nil {
 		return TokenResponse{}, err
 	}
 
+	if response.statusCode != http.StatusOK {
+		return TokenResponse{}, extractAPIError(response)
+	}
+
 	res := TokenResponse{}
 	if err := json.Unmarshal(response.body, &res); err != nil {
 		return TokenResponse{}, fmt.Errorf("error parsing JSON response %w", err)
@@ -160,29 +241,77 @@ func (c *Client) createToken(action string, payload any, expiry int64) (TokenRes
 	return res, nil
 }
 
-func (c *Client) makeRequest(url, method string, body []byte, useBasicAuth bool) (clientResponse, error) {
-	req, err := c.buildRequestContext(clientRequest{
+func (c *Client) makeRequest(
+	ctx context.Context,
+	url, method string,
+	body []byte,
+	useBasicAuth bool,
+) (response clientResponse, err error) {
+	ctx, span := c.tracer().Start(ctx, OperationAPIRequest, map[string]string{"method": method, "path": requestPath(url)})
+	defer func() {
+		if response.statusCode != 0 {
+			span.SetAttribute("status_code", strconv.Itoa(response.statusCode))
+		}
+
+		span.End(err)
+	}()
+
+	request := clientRequest{
 		url:          url,
 		method:       method,
 		body:         body,
 		appUUID:      c.appUUID,
 		apiKey:       c.apiKey,
 		useBasicAuth: useBasicAuth,
-	})
+		token:        c.token,
+	}
+
+	policy := c.Config.RetryPolicy
+
+	for attempt := 1; ; attempt++ {
+		response, err = c.doRequest(ctx, request)
+		if attempt >= policy.attempts() || !shouldRetry(method, response, err) {
+			return response, err
+		}
+
+		c.logger().Warn("retrying evervault API request", "method", method, "url", url, "attempt", attempt,
+			"status", response.statusCode, "error", err)
+
+		backoff := policy.backoff(attempt)
+		if wait := retryAfter(response.header, time.Now()); wait > backoff {
+			// Waiting longer than the policy allows would block the caller, the API error has the wait instead.
+			if policy.MaxBackoff > 0 && wait > policy.MaxBackoff {
+				return response, err
+			}
+
+			backoff = wait
+		}
+
+		if sleepErr := sleepContext(ctx, backoff); sleepErr != nil {
+			return response, fmt.Errorf("error making request %w", sleepErr)
+		}
+	}
+}
+
+func (c *Client) doRequest(ctx context.Context, request clientRequest) (clientResponse, error) {
+	req, err := c.buildRequestContext(ctx, request)
 	if err != nil {
 		return clientResponse{}, fmt.Errorf("error creating request %w", err)
 	}
 
-	client := &http.Client{}
-
-	resp, err := client.Do(req)
+	resp, err := c.httpClient().Do(req)
 	if err != nil {
+		c.logger().Debug("evervault API request failed", "method", request.method, "url", request.url, "error", err)
 		return clientResponse{}, fmt.Errorf("error making request %w", err)
 	}
 
 	defer resp.Body.Close()
 
 	statusCode := resp.StatusCode
+	if statusCode >= http.StatusBadRequest {
+		c.logger().Debug("evervault API request failed", "method", request.method, "url", request.url,
+			"status", statusCode)
+	}
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -191,18 +320,69 @@ func (c *Client) makeRequest(url, method string, body []byte, useBasicAuth bool)
 
 	contentType := resp.Header.Get("Content-Type")
 
-	return clientResponse{respBody, contentType, statusCode}, nil
+	return clientResponse{respBody, contentType, statusCode, resp.Header}, nil
 }
 
-func (c *Client) buildRequestContext(clientRequest clientRequest) (*http.Request, error) {
-	ctx := context.Background()
+// httpClient returns the configured http.Client for requests to the Evervault API.
+func (c *Client) httpClient() *http.Client {
+	if c.Config.HTTPClient != nil {
+		return c.Config.HTTPClient
+	}
+
+	return &http.Client{}
+}
+
+// shouldRetry reports whether a request failed in a way that can be retried. Requests that are not idempotent, such
+// as Function runs, are only retried when they provably did not reach the API: the connection could not be dialed
+// or the API rate limited them.
+func shouldRetry(method string, response clientResponse, err error) bool {
+	if err != nil {
+		var opErr *net.OpError
+
+		return isIdempotent(method) || (errors.As(err, &opErr) && opErr.Op == "dial")
+	}
+
+	switch response.statusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return isIdempotent(method)
+	default:
+		return false
+	}
+}
+
+// isIdempotent reports whether requests with the method can be repeated without changing their effect.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleepContext waits for the duration or until the context is done.
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) buildRequestContext(ctx context.Context, clientRequest clientRequest) (*http.Request, error) {
 	if clientRequest.method == http.MethodGet {
 		req, err := http.NewRequestWithContext(ctx, clientRequest.method, clientRequest.url, nil)
 		if err != nil {
 			return nil, fmt.Errorf("error creating request %w", err)
 		}
 
-		setRequestHeaders(req, clientRequest.appUUID, clientRequest.apiKey, clientRequest.useBasicAuth)
+		setRequestHeaders(req, clientRequest)
 
 		return req, nil
 	}
@@ -214,23 +394,41 @@ func (c *Client) buildRequestContext(clientRequest clientRequest) (*http.Request
 		return nil, fmt.Errorf("error creating request %w", err)
 	}
 
-	setRequestHeaders(req, clientRequest.appUUID, clientRequest.apiKey, clientRequest.useBasicAuth)
+	setRequestHeaders(req, clientRequest)
 
 	return req, nil
 }
 
-func setRequestHeaders(req *http.Request, appUUID, apiKey string, useBasicAuth bool) {
-	if useBasicAuth {
-		stringBytes := []byte(fmt.Sprintf("%s:%s", appUUID, apiKey))
+// requestPath returns the path of a request URL for reporting, without the host or query.
+func requestPath(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	return parsed.Path
+}
+
+// setRequestHeaders authenticates a request with the client-side token of a TokenClient, or the App credentials.
+func setRequestHeaders(req *http.Request, clientRequest clientRequest) {
+	switch {
+	case clientRequest.token != "":
+		req.Header = http.Header{
+			"Authorization": {"Bearer " + clientRequest.token},
+			"Content-Type":  {"application/json"},
+			"user-agent":    {"evervault-go/" + ClientVersion},
+		}
+	case clientRequest.useBasicAuth:
+		stringBytes := []byte(fmt.Sprintf("%s:%s", clientRequest.appUUID, clientRequest.apiKey))
 		base64EncodedHeaderValue := base64.StdEncoding.EncodeToString(stringBytes)
 		req.Header = http.Header{
 			"Authorization": {"Basic " + base64EncodedHeaderValue},
 			"Content-Type":  {"application/json"},
 			"user-agent":    {"evervault-go/" + ClientVersion},
 		}
-	} else {
+	default:
 		req.Header = http.Header{
-			"API-KEY":      {apiKey},
+			"API-KEY":      {clientRequest.apiKey},
 			"Content-Type": {"application/json"},
 			"user-agent":   {"evervault-go/" + ClientVersion},
 		}
@@ -1,49 +1,287 @@
 package evervault
 
 import (
+	"fmt"
+	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"time"
+
+	internalAttestation "github.com/evervault/evervault-go/internal/attestation"
+	"github.com/evervault/evervault-go/internal/logging"
 )
 
 // Config holds the configuration for the Evervault Client.
 type Config struct {
-	EvervaultCaURL             string        // URL for the Evervault CA.
-	EvervaultCagesCaURL        string        // URL for the Evervault Cages CA.
-	RelayURL                   string        // URL for the Evervault Relay.
-	EvAPIURL                   string        // URL for the Evervault API.
-	CagesPollingInterval       time.Duration // Polling interval for obtaining fresh attestation doc in seconds
-	AttestationPollingInterval time.Duration // Polling interval for obtaining fresh attestation doc in seconds
+	EvervaultCaURL             string          // URL for the Evervault CA.
+	EvervaultCaPath            string          // Path of a PEM file with the Evervault CA, used instead of the URL.
+	EvervaultCaRefreshInterval time.Duration   // How long the downloaded Evervault CA is cached, 1 hour if zero.
+	EvervaultCagesCaURL        string          // URL for the Evervault Cages CA.
+	RelayURL                   string          // URL for the Evervault Relay.
+	EvAPIURL                   string          // URL for the Evervault API.
+	CagesPollingInterval       time.Duration   // Polling interval for obtaining fresh attestation doc, 2 minutes if zero.
+	AttestationPollingInterval time.Duration   // Polling interval for obtaining fresh attestation doc, 2 minutes if zero.
+	Logger                     Logger          // Logger for background failures, defaults to the standard log package.
+	Instrumentation            Instrumentation // Instrumentation notified of every operation, for tracing and metrics.
+	HTTPClient                 *http.Client    // HTTP client for requests to the Evervault API.
+	RetryPolicy                RetryPolicy     // Retry policy for requests to the Evervault API.
+	AppPublicKey               []byte          // Uncompressed App public key, fetched from the API when empty.
+	EnclaveDialTimeout         time.Duration   // Timeout for connecting to an enclave and the TLS handshake, 5s if zero.
+	AttestationDocTimeout      time.Duration   // Timeout for fetching an attestation doc including retries, 30s if zero.
+	AttestationRetryPolicy     RetryPolicy     // Retry policy for attestation doc fetches, 3 attempts if zero.
+	DecryptionDomains          []string        // Hosts sent through Outbound Relay, "*." matches subdomains. All if empty.
+	FunctionRunPollingInterval time.Duration   // Interval WaitFunctionRun polls a Function run at, 1 second if zero.
+}
+
+// RetryPolicy controls how requests to the Evervault API are retried. Requests are retried when they fail to
+// connect or the API responds with 429. Idempotent requests are also retried when they fail after connecting or the
+// API responds with 502, 503 or 504, requests such as Function runs are not as they may already have been
+// processed. The Retry-After header of the response is waited for if it is longer than the backoff. If it is longer
+// than MaxBackoff the request is not retried, and the RateLimitError returned has the RetryAfter of the response.
+// The zero value disables retries.
+type RetryPolicy struct {
+	MaxAttempts    int           // Total number of attempts, including the first request.
+	InitialBackoff time.Duration // Delay before the first retry, doubled for every following retry.
+	MaxBackoff     time.Duration // Maximum delay between retries, unbounded when zero.
+}
+
+// valid reports whether none of the policy values are negative.
+func (p RetryPolicy) valid() bool {
+	return p.MaxAttempts >= 0 && p.InitialBackoff >= 0 && p.MaxBackoff >= 0
+}
+
+// attempts returns the total number of attempts to make, at least one.
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+
+	return p.MaxAttempts
+}
+
+// backoff returns the delay before the retry following the given attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt && (p.MaxBackoff == 0 || backoff < p.MaxBackoff); i++ {
+		backoff *= 2
+	}
+
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+
+	return backoff
 }
 
+const (
+	defaultCaURL           = "https://ca.evervault.com"
+	defaultCagesCaURL      = "https://cages-ca.evervault.com/cages-ca.crt"
+	defaultRelayURL        = "https://relay.evervault.com"
+	defaultAPIURL          = "https://api.evervault.com"
+	defaultPollingInterval = 120 * time.Second
+	defaultCaRefresh       = time.Hour
+	defaultFunctionPolling = time.Second
+
+	defaultEnclaveDialTimeout    = 5 * time.Second
+	defaultAttestationDocTimeout = internalAttestation.DefaultFetchTimeout
+)
+
+// defaultAttestationRetryPolicy retries attestation doc fetches after 2 and 4 seconds.
+var defaultAttestationRetryPolicy = RetryPolicy{
+	MaxAttempts:    internalAttestation.DefaultMaxAttempts,
+	InitialBackoff: internalAttestation.DefaultBackoff,
+}
+
+// defaultConfig returns the Evervault defaults without reading environment variables.
+func defaultConfig() Config {
+	return Config{
+		EvervaultCaURL:             defaultCaURL,
+		EvervaultCagesCaURL:        defaultCagesCaURL,
+		EvervaultCaRefreshInterval: defaultCaRefresh,
+		RelayURL:                   defaultRelayURL,
+		EvAPIURL:                   defaultAPIURL,
+		CagesPollingInterval:       defaultPollingInterval,
+		AttestationPollingInterval: defaultPollingInterval,
+		EnclaveDialTimeout:         defaultEnclaveDialTimeout,
+		AttestationDocTimeout:      defaultAttestationDocTimeout,
+		AttestationRetryPolicy:     defaultAttestationRetryPolicy,
+		FunctionRunPollingInterval: defaultFunctionPolling,
+	}
+}
+
+// Logger receives structured log messages from the Client, such as failed attestation doc and PCR refreshes.
+// Args are alternating key value pairs. A *slog.Logger can be used as a Logger.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// DiscardLogger is a Logger that drops every message. It can be set as Config.Logger to silence the Client.
+var DiscardLogger Logger = logging.Discard{}
+
 // MakeConfig loads the Evervault client configuration from environment variables.
-// It falls back to default values if the environment variables are not set.
+// It falls back to default values if the environment variables are not set or a polling interval cannot be parsed.
 func MakeConfig() Config {
 	return Config{
-		EvervaultCaURL:             getEnvOrDefault("EV_CA_URL", "https://ca.evervault.com"),
-		EvervaultCagesCaURL:        getEnvOrDefault("EV_CAGES_CA_URL", "https://cages-ca.evervault.com/cages-ca.crt"),
-		RelayURL:                   getEnvOrDefault("EV_RELAY_URL", "https://relay.evervault.com"),
-		EvAPIURL:                   getEnvOrDefault("EV_API_URL", "https://api.evervault.com"),
+		EvervaultCaURL:             getEnvOrDefault("EV_CA_URL", defaultCaURL),
+		EvervaultCagesCaURL:        getEnvOrDefault("EV_CAGES_CA_URL", defaultCagesCaURL),
+		EvervaultCaPath:            os.Getenv("EV_CA_PATH"),
+		EvervaultCaRefreshInterval: defaultCaRefresh,
+		RelayURL:                   getEnvOrDefault("EV_RELAY_URL", defaultRelayURL),
+		EvAPIURL:                   getEnvOrDefault("EV_API_URL", defaultAPIURL),
 		CagesPollingInterval:       getAttestationPollingInterval(),
 		AttestationPollingInterval: getAttestationPollingInterval(),
+		EnclaveDialTimeout:         defaultEnclaveDialTimeout,
+		AttestationDocTimeout:      defaultAttestationDocTimeout,
+		AttestationRetryPolicy:     defaultAttestationRetryPolicy,
+		FunctionRunPollingInterval: defaultFunctionPolling,
 	}
 }
 
-func getAttestationPollingInterval() time.Duration {
-	const defaultPollingInterval = 120
+// Validate checks that the Config can be used to create a Client. It returns an error wrapping ErrInvalidConfig if
+// a polling interval is not positive, a timeout or retry policy is negative or a URL is not an absolute http or
+// https URL.
+//
+// MakeCustomClient replaces unset URLs, polling intervals and timeouts with the Evervault defaults before calling
+// Validate, so a zero polling interval uses the default and only negative intervals are rejected there.
+func (c Config) Validate() error {
+	urls := []struct{ name, value string }{
+		{"API URL", c.EvAPIURL},
+		{"Relay URL", c.RelayURL},
+		{"CA URL", c.EvervaultCaURL},
+		{"Cages CA URL", c.EvervaultCagesCaURL},
+	}
+
+	for _, configURL := range urls {
+		if err := validateURL(configURL.name, configURL.value); err != nil {
+			return err
+		}
+	}
+
+	if c.AttestationPollingInterval <= 0 {
+		return fmt.Errorf("%w: attestation polling interval must be positive, got %s",
+			ErrInvalidConfig, c.AttestationPollingInterval)
+	}
+
+	if c.CagesPollingInterval <= 0 {
+		return fmt.Errorf("%w: cages polling interval must be positive, got %s", ErrInvalidConfig, c.CagesPollingInterval)
+	}
+
+	if c.EnclaveDialTimeout < 0 || c.AttestationDocTimeout < 0 {
+		return fmt.Errorf("%w: enclave timeouts must not be negative", ErrInvalidConfig)
+	}
+
+	if c.EvervaultCaRefreshInterval < 0 {
+		return fmt.Errorf("%w: CA refresh interval must not be negative", ErrInvalidConfig)
+	}
+
+	if c.FunctionRunPollingInterval < 0 {
+		return fmt.Errorf("%w: function run polling interval must not be negative", ErrInvalidConfig)
+	}
+
+	if !c.RetryPolicy.valid() || !c.AttestationRetryPolicy.valid() {
+		return fmt.Errorf("%w: retry policy values must not be negative", ErrInvalidConfig)
+	}
 
+	return nil
+}
+
+// withDefaults returns a copy of the Config with unset URLs, polling intervals and enclave timeouts replaced by the
+// Evervault defaults.
+func (c Config) withDefaults() Config {
+	defaults := defaultConfig()
+
+	if c.EvervaultCaURL == "" {
+		c.EvervaultCaURL = defaults.EvervaultCaURL
+	}
+
+	if c.EvervaultCagesCaURL == "" {
+		c.EvervaultCagesCaURL = defaults.EvervaultCagesCaURL
+	}
+
+	if c.EvervaultCaRefreshInterval == 0 {
+		c.EvervaultCaRefreshInterval = defaults.EvervaultCaRefreshInterval
+	}
+
+	if c.RelayURL == "" {
+		c.RelayURL = defaults.RelayURL
+	}
+
+	if c.EvAPIURL == "" {
+		c.EvAPIURL = defaults.EvAPIURL
+	}
+
+	if c.CagesPollingInterval == 0 {
+		c.CagesPollingInterval = defaults.CagesPollingInterval
+	}
+
+	if c.AttestationPollingInterval == 0 {
+		c.AttestationPollingInterval = defaults.AttestationPollingInterval
+	}
+
+	if c.EnclaveDialTimeout == 0 {
+		c.EnclaveDialTimeout = defaults.EnclaveDialTimeout
+	}
+
+	if c.AttestationDocTimeout == 0 {
+		c.AttestationDocTimeout = defaults.AttestationDocTimeout
+	}
+
+	if c.AttestationRetryPolicy.MaxAttempts == 0 {
+		c.AttestationRetryPolicy = defaults.AttestationRetryPolicy
+	}
+
+	if c.FunctionRunPollingInterval == 0 {
+		c.FunctionRunPollingInterval = defaults.FunctionRunPollingInterval
+	}
+
+	return c
+}
+
+// validateURL checks that a configured URL is an absolute http or https URL.
+func validateURL(name, rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %s %q: %w", ErrInvalidConfig, name, rawURL, err)
+	}
+
+	if parsed.Scheme != "https" && parsed.Scheme != "http" {
+		return fmt.Errorf("%w: %s %q must use http or https", ErrInvalidConfig, name, rawURL)
+	}
+
+	if parsed.Host == "" {
+		return fmt.Errorf("%w: %s %q has no host", ErrInvalidConfig, name, rawURL)
+	}
+
+	return nil
+}
+
+// getAttestationPollingInterval reads the polling interval from EV_ATTESTATION_POLLING_INTERVAL, falling back to
+// EV_CAGES_POLLING_INTERVAL. Values are either a Go duration such as "2m" or a number of seconds.
+func getAttestationPollingInterval() time.Duration {
 	intervalStr := os.Getenv("EV_ATTESTATION_POLLING_INTERVAL")
 
 	if intervalStr == "" {
-		intervalStr = getEnvOrDefault("EV_CAGES_POLLING_INTERVAL", "120")
+		intervalStr = os.Getenv("EV_CAGES_POLLING_INTERVAL")
+	}
+
+	if intervalStr == "" {
+		return defaultPollingInterval
+	}
+
+	if interval, err := time.ParseDuration(intervalStr); err == nil {
+		return interval
 	}
 
-	interval, err := strconv.ParseInt(intervalStr, 10, 64)
+	seconds, err := strconv.ParseInt(intervalStr, 10, 64)
 	if err != nil {
 		return defaultPollingInterval
 	}
 
-	return time.Duration(interval) * time.Second
+	return time.Duration(seconds) * time.Second
 }
 
 // getEnvOrDefault retrieves the value of an environment variable or returns a default value if not set.
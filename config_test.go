@@ -0,0 +1,93 @@
+//go:build unit_test
+// +build unit_test
+
+package evervault_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evervault/evervault-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeConfigPollingInterval(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":        2 * time.Minute,
+		"30":      30 * time.Second,
+		"2m":      2 * time.Minute,
+		"1m30s":   90 * time.Second,
+		"invalid": 2 * time.Minute,
+	}
+
+	for value, expected := range tests {
+		t.Setenv("EV_ATTESTATION_POLLING_INTERVAL", value)
+
+		config := evervault.MakeConfig()
+		assert.Equal(t, expected, config.AttestationPollingInterval, value)
+		assert.Equal(t, expected, config.CagesPollingInterval, value)
+	}
+}
+
+func TestMakeConfigFallsBackToCagesPollingInterval(t *testing.T) {
+	t.Setenv("EV_ATTESTATION_POLLING_INTERVAL", "")
+	t.Setenv("EV_CAGES_POLLING_INTERVAL", "45s")
+
+	config := evervault.MakeConfig()
+	assert.Equal(t, 45*time.Second, config.AttestationPollingInterval)
+}
+
+func TestConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, evervault.MakeConfig().Validate())
+
+	tests := map[string]func(*evervault.Config){
+		"zero interval":     func(c *evervault.Config) { c.AttestationPollingInterval = 0 },
+		"negative interval": func(c *evervault.Config) { c.CagesPollingInterval = -time.Second },
+		"empty URL":         func(c *evervault.Config) { c.EvAPIURL = "" },
+		"missing scheme":    func(c *evervault.Config) { c.RelayURL = "relay.evervault.com" },
+		"unsupported":       func(c *evervault.Config) { c.EvervaultCaURL = "ftp://ca.evervault.com" },
+		"missing host":      func(c *evervault.Config) { c.EvervaultCagesCaURL = "https://" },
+		"negative timeout":  func(c *evervault.Config) { c.EnclaveDialTimeout = -time.Second },
+		"negative retries":  func(c *evervault.Config) { c.AttestationRetryPolicy.MaxAttempts = -1 },
+	}
+
+	for name, modify := range tests {
+		config := evervault.MakeConfig()
+		modify(&config)
+
+		assert.ErrorIs(t, config.Validate(), evervault.ErrInvalidConfig, name)
+	}
+}
+
+func TestMakeCustomClientValidatesConfig(t *testing.T) {
+	t.Parallel()
+
+	config := evervault.Config{AttestationPollingInterval: -time.Minute}
+
+	_, err := evervault.MakeCustomClient("test_app_uuid", "test_api_key", config)
+	assert.ErrorIs(t, err, evervault.ErrInvalidConfig)
+
+	config = evervault.Config{EvAPIURL: "api.evervault.com"}
+
+	_, err = evervault.MakeCustomClient("test_app_uuid", "test_api_key", config)
+	assert.ErrorIs(t, err, evervault.ErrInvalidConfig)
+}
+
+func TestMakeCustomClientAppliesDefaults(t *testing.T) {
+	t.Parallel()
+
+	server := startMockHTTPServer(nil, "")
+	defer server.Close()
+
+	client := mockedClient(t, server)
+
+	assert.NoError(t, client.Config.Validate())
+	assert.Equal(t, 2*time.Minute, client.Config.AttestationPollingInterval)
+	assert.Equal(t, 2*time.Minute, client.Config.CagesPollingInterval)
+	assert.Equal(t, "https://cages-ca.evervault.com/cages-ca.crt", client.Config.EvervaultCagesCaURL)
+	assert.Equal(t, 5*time.Second, client.Config.EnclaveDialTimeout)
+	assert.Equal(t, 30*time.Second, client.Config.AttestationDocTimeout)
+	assert.Equal(t, 3, client.Config.AttestationRetryPolicy.MaxAttempts)
+}
@@ -3,13 +3,98 @@ package evervault
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"net"
 	"net/http"
+	"reflect"
+	"sync"
+	"time"
 
 	"github.com/evervault/evervault-go/attestation"
 	internalAttestation "github.com/evervault/evervault-go/internal/attestation"
+	"github.com/evervault/evervault-go/internal/logging"
+	"github.com/evervault/evervault-go/internal/telemetry"
 )
 
+// EnclaveOption configures how connections to an enclave are attested.
+type EnclaveOption func(*enclaveOptions)
+
+type enclaveOptions struct {
+	maxDocAge        time.Duration
+	challenge        bool
+	dialTimeout      time.Duration
+	docTimeout       time.Duration
+	attestationRoots *x509.CertPool
+	tlsRoots         *x509.CertPool
+}
+
+// WithMaxAttestationDocAge rejects attestation docs that were issued more than maxAge ago. When the cached
+// doc is too old a fresh doc is fetched before the connection is attested.
+//
+// The attestation doc cache is refreshed every Config.AttestationPollingInterval, so maxAge should be longer
+// than the polling interval to avoid fetching a doc on every connection.
+func WithMaxAttestationDocAge(maxAge time.Duration) EnclaveOption {
+	return func(o *enclaveOptions) {
+		o.maxDocAge = maxAge
+	}
+}
+
+// WithAttestationChallenge requests a fresh attestation doc for every connection, bound to a random nonce
+// generated by the client. The connection is only attested if the doc contains the nonce, preventing a
+// previously issued doc from being replayed. This adds a request to the enclave for every connection.
+func WithAttestationChallenge() EnclaveOption {
+	return func(o *enclaveOptions) {
+		o.challenge = true
+	}
+}
+
+// WithDialTimeout overrides Config.EnclaveDialTimeout, the time allowed to connect to the enclave and complete
+// the TLS handshake. The deadline of the context passed to the dial is also honored.
+func WithDialTimeout(timeout time.Duration) EnclaveOption {
+	return func(o *enclaveOptions) {
+		o.dialTimeout = timeout
+	}
+}
+
+// WithAttestationDocTimeout overrides Config.AttestationDocTimeout for the attestation docs fetched while a
+// connection is attested, when the cached doc is rejected or WithAttestationChallenge is used.
+func WithAttestationDocTimeout(timeout time.Duration) EnclaveOption {
+	return func(o *enclaveOptions) {
+		o.docTimeout = timeout
+	}
+}
+
+// WithAttestationRoots verifies the certificates attestation docs are signed with against roots instead of the
+// AWS Nitro Enclaves root. It is intended for testing against enclaves with synthetic attestation docs, such as
+// evervaulttest.Enclave, and must not be used with production enclaves.
+func WithAttestationRoots(roots *x509.CertPool) EnclaveOption {
+	return func(o *enclaveOptions) {
+		o.attestationRoots = roots
+	}
+}
+
+// WithEnclaveRootCAs verifies the TLS certificate of the enclave against roots instead of the system roots, for
+// connections to the enclave and attestation doc requests. It is intended for testing against enclaves with
+// self-signed certificates. Attestation docs are only shared with sessions trusting the same pool.
+func WithEnclaveRootCAs(roots *x509.CertPool) EnclaveOption {
+	return func(o *enclaveOptions) {
+		o.tlsRoots = roots
+	}
+}
+
+func (c *Client) buildEnclaveOptions(opts []EnclaveOption) enclaveOptions {
+	options := enclaveOptions{
+		dialTimeout: c.Config.EnclaveDialTimeout,
+		docTimeout:  c.Config.AttestationDocTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
+
 // Will return a http.Client that is connected to a specified enclave hostname with a fully attested client.
 // The Client will attest the connection every time it makes a HTTP request and will return an error on request if it
 // fails attestation
@@ -38,10 +123,20 @@ import (
 //	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
 //
 //	resp, err := enclaveClient.Do(req)
-func (c *Client) EnclaveClient(enclaveHostname string, pcrs []attestation.PCRs) (*http.Client, error) {
+//
+// Options can be passed to require fresh attestation docs, see WithMaxAttestationDocAge and
+// WithAttestationChallenge.
+//
+// The client polls for PCRs and attestation docs in the background until Client.Close is called. Use OpenEnclave
+// for a session that can be closed on its own.
+func (c *Client) EnclaveClient(
+	enclaveHostname string,
+	pcrs []attestation.PCRs,
+	opts ...EnclaveOption,
+) (*http.Client, error) {
 	provider := attestation.BuildStaticPcrProvider(pcrs)
 
-	client, err := c.EnclaveClientWithProvider(enclaveHostname, provider)
+	client, err := c.EnclaveClientWithProvider(enclaveHostname, provider, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -77,21 +172,20 @@ func (c *Client) EnclaveClient(enclaveHostname string, pcrs []attestation.PCRs)
 //	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
 //
 //	resp, err := enclaveClient.Do(req)
+//
+// The client polls for PCRs and attestation docs in the background until Client.Close is called. Use OpenEnclave
+// for a session that can be closed on its own.
 func (c *Client) EnclaveClientWithProvider(
 	enclaveHostname string,
 	pcrsProvider func() ([]attestation.PCRs, error),
+	opts ...EnclaveOption,
 ) (*http.Client, error) {
-	customDial, err := c.EnclaveTCPConnectionWithProvider(enclaveHostname, pcrsProvider)
+	session, err := c.OpenEnclave(enclaveHostname, pcrsProvider, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	transport := &http.Transport{
-		DisableKeepAlives: true,
-		DialTLSContext:    customDial,
-	}
-
-	return &http.Client{Transport: transport}, nil
+	return session.HTTPClient(), nil
 }
 
 // Will return a http.Client that is connected to a specified enclave hostname with a fully attested client.
@@ -128,28 +222,170 @@ func (c *Client) EnclaveClientWithProvider(
 //		if _, err := conn.Write([]byte("Hello, World!")); err != nil {
 //	 	log.Fatal(err)
 //		}
+//
+// The dial function polls for PCRs and attestation docs in the background until Client.Close is called. Use
+// OpenEnclave for a session that can be closed on its own.
 func (c *Client) EnclaveTCPConnectionWithProvider(
 	enclaveHostname string,
 	pcrsProvider func() ([]attestation.PCRs, error),
+	opts ...EnclaveOption,
 ) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
-	pcrManager := internalAttestation.NewPollingPCRManager(c.Config.AttestationPollingInterval, pcrsProvider)
+	session, err := c.OpenEnclave(enclaveHostname, pcrsProvider, opts...)
+	if err != nil {
+		return nil, err
+	}
 
-	expectedPcrs := pcrManager.Get()
+	return session.DialContext, nil
+}
 
-	if len(filterEmptyPCRs(*expectedPcrs)) == 0 {
-		return nil, ErrNoPCRs
+// EnclaveSession holds the attestation state for connections to an enclave. The PCRs and attestation doc
+// are refreshed in the background until the session is closed.
+//
+// Attestation docs are cached per enclave hostname and shared by every session in the process with the same
+// polling interval, attestation doc timeout, retry policy, enclave root CAs, Logger and Instrumentation, so opening
+// sessions for the same enclave does not add extra requests to the enclave.
+type EnclaveSession struct {
+	client       *Client
+	pcrManager   internalAttestation.PCRManager
+	releaseCache func()
+	dial         func(ctx context.Context, network, addr string) (net.Conn, error)
+	closeOnce    sync.Once
+}
+
+// OpenEnclave returns an EnclaveSession for a specified enclave hostname. Connections made through the session are
+// attested every time they are dialed, using the PCRs returned by the provider.
+//
+// The session polls for PCRs and attestation docs in the background. Call Close once it is no longer needed,
+// or Client.Close to close every session opened by the Client.
+//
+//	session, err := evClient.OpenEnclave(enclaveURL, attestation.FileProvider("enclave.toml"))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer session.Close()
+//
+//	resp, err := session.HTTPClient().Do(req)
+func (c *Client) OpenEnclave(
+	enclaveHostname string,
+	pcrsProvider func() ([]attestation.PCRs, error),
+	opts ...EnclaveOption,
+) (*EnclaveSession, error) {
+	if c.isClosed() {
+		return nil, ErrClientClosed
 	}
 
-	cache, err := internalAttestation.NewAttestationCache(enclaveHostname, c.Config.AttestationPollingInterval)
+	pcrManager := internalAttestation.NewPollingPCRManager(c.Config.AttestationPollingInterval, pcrsProvider,
+		logging.With(c.logger(), "hostname", enclaveHostname),
+		telemetry.WithAttributes(c.tracer(), map[string]string{"hostname": enclaveHostname}))
+
+	return c.openSession(enclaveHostname, pcrManager, c.Config.AttestationPollingInterval, c.buildEnclaveOptions(opts))
+}
+
+func (c *Client) openSession(
+	enclaveHostname string,
+	pcrManager internalAttestation.PCRManager,
+	pollingInterval time.Duration,
+	options enclaveOptions,
+) (*EnclaveSession, error) {
+	if _, err := filterEmptyPCRs(*pcrManager.Get()); err != nil {
+		pcrManager.StopPolling()
+		return nil, err
+	}
+
+	retryPolicy := c.Config.AttestationRetryPolicy
+
+	cacheKey := internalAttestation.CacheKey{
+		Hostname:        enclaveHostname,
+		PollingInterval: pollingInterval,
+		FetchTimeout:    c.Config.AttestationDocTimeout,
+		MaxAttempts:     retryPolicy.MaxAttempts,
+		Backoff:         retryPolicy.InitialBackoff,
+		MaxBackoff:      retryPolicy.MaxBackoff,
+		RootCAs:         options.tlsRoots,
+		Owner:           c.cacheOwner(),
+	}
+
+	cache, releaseCache, err := internalAttestation.DefaultRegistry.Acquire(cacheKey,
+		internalAttestation.WithLogger(c.logger()), internalAttestation.WithTracer(c.tracer()))
 	if err != nil {
+		pcrManager.StopPolling()
 		return nil, err
 	}
 
 	tlsConfig := &tls.Config{
 		InsecureSkipVerify: false,
 		MinVersion:         tls.VersionTLS12,
-		ServerName:         enclaveHostname,
+		ServerName:         serverName(enclaveHostname),
+		RootCAs:            options.tlsRoots,
 	}
 
-	return c.createDial(tlsConfig, cache, pcrManager), nil
+	session := &EnclaveSession{
+		client:       c,
+		pcrManager:   pcrManager,
+		releaseCache: releaseCache,
+		dial:         c.createDial(tlsConfig, cache, pcrManager, options),
+	}
+
+	if err := c.trackSession(session); err != nil {
+		session.stop()
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// cacheOwner identifies the Logger and Instrumentation of the Client, so shared attestation doc caches only log and
+// trace to the Clients using them. Values that cannot be compared are identified by the Client instead.
+func (c *Client) cacheOwner() any {
+	owner := [2]any{c.Config.Logger, c.Config.Instrumentation}
+	if isComparable(owner[0]) && isComparable(owner[1]) {
+		return owner
+	}
+
+	return c
+}
+
+func isComparable(value any) bool {
+	return value == nil || reflect.ValueOf(value).Comparable()
+}
+
+// serverName returns the hostname the TLS certificate of an enclave is verified against, without a port.
+func serverName(enclaveHostname string) string {
+	if host, _, err := net.SplitHostPort(enclaveHostname); err == nil {
+		return host
+	}
+
+	return enclaveHostname
+}
+
+// DialContext dials the enclave and attests the connection. It can be used as the DialTLSContext of an
+// http.Transport.
+func (s *EnclaveSession) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return s.dial(ctx, network, addr)
+}
+
+// HTTPClient returns a http.Client that attests every connection it makes to the enclave.
+func (s *EnclaveSession) HTTPClient() *http.Client {
+	transport := &http.Transport{
+		DisableKeepAlives: true,
+		DialTLSContext:    s.dial,
+	}
+
+	return &http.Client{Transport: transport}
+}
+
+// Close stops polling for PCRs and attestation docs. Connections dialed after Close are still attested
+// against the last PCRs and attestation doc retrieved. It is safe to call Close more than once.
+func (s *EnclaveSession) Close() error {
+	s.stop()
+	s.client.untrackSession(s)
+
+	return nil
+}
+
+func (s *EnclaveSession) stop() {
+	s.closeOnce.Do(func() {
+		s.pcrManager.StopPolling()
+		s.releaseCache()
+	})
 }
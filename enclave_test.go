@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/evervault/evervault-go"
 	"github.com/evervault/evervault-go/attestation"
@@ -110,7 +111,8 @@ func TestEnclavePartialPCR(t *testing.T) {
 	}
 
 	expectedPCRs := attestation.PCRs{
-		PCR8: "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		PCR8:          "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		AllowWildcard: true,
 	}
 
 	enclaveClient, err := testClient.EnclaveClient(enclave, []attestation.PCRs{expectedPCRs})
@@ -237,8 +239,9 @@ func TestEnclaveFailsOnPartialIncorrectPCR(t *testing.T) {
 	}
 
 	expectedPCRs := attestation.PCRs{
-		PCR0: "111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111",
-		PCR8: "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		PCR0:          "111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111",
+		PCR8:          "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		AllowWildcard: true,
 	}
 
 	enclaveClient, err := testClient.EnclaveClient(enclave, []attestation.PCRs{expectedPCRs})
@@ -273,3 +276,68 @@ func TestEnclaveRequiresPCR(t *testing.T) {
 	_, err = testClient.EnclaveClient(enclave, []attestation.PCRs{})
 	assert.ErrorIs(err, evervault.ErrNoPCRs)
 }
+
+func TestEnclaveRejectsMalformedPCR(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	server := startMockHTTPServer("", "")
+	defer server.Close()
+
+	config := evervault.Config{
+		EvAPIURL:                   server.URL,
+		AttestationPollingInterval: time.Minute,
+	}
+
+	testClient, err := evervault.MakeCustomClient("test_api_key", "test_app_uuid", config)
+	if err != nil {
+		t.Errorf("Error creating evervault client: %s", err)
+		return
+	}
+
+	malformedPCRs := attestation.PCRs{
+		PCR0: "INVALID00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		PCR1: "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		PCR2: "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		PCR8: "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+	}
+
+	_, err = testClient.EnclaveClient(enclave, []attestation.PCRs{malformedPCRs})
+	assert.ErrorIs(err, evervault.ErrInvalidPCRs)
+	assert.ErrorIs(err, attestation.ErrMalformedPCR)
+
+	partialPCRs := attestation.PCRs{
+		PCR8: "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+	}
+
+	_, err = testClient.EnclaveClient(enclave, []attestation.PCRs{partialPCRs})
+	assert.ErrorIs(err, attestation.ErrWildcardPCR)
+}
+
+func TestEnclaveClientAfterClose(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	server := startMockHTTPServer("", "")
+	defer server.Close()
+
+	testClient := mockedClient(t, server)
+
+	assert.Nil(testClient.Close())
+	assert.Nil(testClient.Close())
+
+	expectedPCRs := attestation.PCRs{
+		PCR0: "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		PCR1: "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		PCR2: "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		PCR8: "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+	}
+
+	_, err := testClient.EnclaveClient(enclave, []attestation.PCRs{expectedPCRs})
+	assert.ErrorIs(err, evervault.ErrClientClosed)
+
+	_, err = testClient.OpenEnclave(enclave, attestation.BuildStaticPcrProvider([]attestation.PCRs{expectedPCRs}))
+	assert.ErrorIs(err, evervault.ErrClientClosed)
+}
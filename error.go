@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
+	"time"
 )
 
 // ErrUnVerifiedSignature is returned when a attestation docs signature cant be verified.
@@ -12,24 +15,50 @@ var ErrUnVerifiedSignature = errors.New("unable to verify certificate signature"
 // ErrNoPCRs is returned when a PCRs is created without any PCR in it to attest with.
 var ErrNoPCRs = errors.New("Error: no PCRs where provided to attest with")
 
+// ErrInvalidPCRs is returned when a PCR set provided to attest with is malformed.
+var ErrInvalidPCRs = errors.New("invalid PCRs provided to attest with")
+
 // ErrInvalidPCRProvider is returned when an invalid PCR provider type is passed to CagesClient.
 var ErrInvalidPCRProvider = errors.New("unsupported type, must be array or callback: func() ([]types.PCRs, error)")
 
 // ErrAttestionFailure is retuned when a connection to a cage cannot be attested.
 var ErrAttestionFailure = errors.New("attestation failed")
 
+// ErrStaleAttestationDoc is returned when an attestation doc is older than the configured maximum age.
+var ErrStaleAttestationDoc = errors.New("attestation doc is older than the maximum allowed age")
+
+// ErrAttestationNonceMismatch is returned when an attestation doc is not bound to the nonce it was requested with.
+var ErrAttestationNonceMismatch = errors.New("attestation doc nonce does not match challenge")
+
 // ErrClientNotInitilization is returned when Evervault client has not been initialized.
 var ErrClientNotInitilization = errors.New("evervault client unable to initialize")
 
+// ErrClientClosed is returned when an Enclave client is requested from a Client that has been closed.
+var ErrClientClosed = errors.New("evervault client has been closed")
+
 // ErrAppCredentialsRequired is returned when the required application credentials for initialisation are missing.
 var ErrAppCredentialsRequired = errors.New("evervault client requires an api key and app uuid")
 
+// ErrInvalidConfig is returned when the Client is configured with a malformed value.
+var ErrInvalidConfig = errors.New("invalid evervault client configuration")
+
+// ErrInvalidCACertificate is returned when the Evervault CA used by Outbound Relay is not a PEM encoded certificate.
+var ErrInvalidCACertificate = errors.New("evervault CA certificate could not be parsed")
+
 // ErrCryptoKeyImportError is returned when the client is unable to the import Keys for crypto.
 var ErrCryptoKeyImportError = errors.New("unable to import crypto key")
 
 // ErrCryptoUnableToPerformEncryption is reutrned when the encryption function is unable to encrypt data.
 var ErrCryptoUnableToPerformEncryption = errors.New("unable to perform encryption")
 
+// ErrInvalidTokenExpiry is returned when a token is requested with an expiry that is not in the future, or a
+// client-side token with an expiry more than 10 minutes from now.
+var ErrInvalidTokenExpiry = errors.New("token expiry must be in the future")
+
+// ErrTokenRejected is returned by a TokenClient when the Evervault API rejects its client-side token, because the
+// token has expired or was not created for the payload being decrypted.
+var ErrTokenRejected = errors.New("client-side token has expired or is not valid for the payload")
+
 // ErrInvalidDataType is returned when an unsupported data type was specified for encryption.
 var ErrInvalidDataType = errors.New("Error: Invalid datatype")
 
@@ -37,38 +66,212 @@ var ErrInvalidDataType = errors.New("Error: Invalid datatype")
 // Only TCP is supported for Enclaves.
 var ErrUnsupportedNetworkType = errors.New("error: unsupported network type")
 
+// ErrUnknownFunctionRunStatus is returned when a Function run has a status that is neither finished nor pending.
+var ErrUnknownFunctionRunStatus = errors.New("unknown function run status")
+
+// ErrNotFound is matched by errors.Is for API errors with a 404 Not Found status.
+var ErrNotFound = errors.New("evervault resource not found")
+
+// ErrUnauthorized is matched by errors.Is for API errors with a 401 Unauthorized status.
+var ErrUnauthorized = errors.New("evervault request unauthorized")
+
+// ErrForbidden is matched by errors.Is for API errors with a 403 Forbidden status.
+var ErrForbidden = errors.New("evervault request forbidden")
+
+// ErrRateLimited is matched by errors.Is for API errors with a 429 Too Many Requests status.
+var ErrRateLimited = errors.New("evervault request rate limited")
+
+// ExtractAPIError parses an error response body from the Evervault API. The error is typed by the status in the
+// body, as errors returned by the Client are. An error is returned if the body is not JSON.
 func ExtractAPIError(resp []byte) error {
-	evervaultError := APIError{}
-	if err := json.Unmarshal(resp, &evervaultError); err != nil {
+	body := apiErrorBody{}
+	if err := json.Unmarshal(resp, &body); err != nil {
 		return fmt.Errorf("Error parsing JSON response %w", err)
 	}
 
+	return extractAPIError(clientResponse{body: resp})
+}
+
+// apiErrorBody is an error response from the Evervault API.
+type apiErrorBody struct {
+	APIError
+	Status int          `json:"status"`
+	Errors []FieldError `json:"errors"`
+}
+
+// extractAPIError returns the error for an unsuccessful response from the Evervault API, typed by the status of
+// the response and with its status code and request ID. Responses without a status use the status in the body.
+// Responses without an Evervault error body are described by their status.
+func extractAPIError(response clientResponse) error {
+	body := apiErrorBody{}
+	err := json.Unmarshal(response.body, &body)
+
+	if response.statusCode == 0 {
+		response.statusCode = body.Status
+	}
+
+	if (err != nil || body.Message == "") && response.statusCode != 0 {
+		body.Message = fmt.Sprintf("unexpected response from Evervault API: %d %s",
+			response.statusCode, http.StatusText(response.statusCode))
+	}
+
+	evervaultError := body.APIError
+	evervaultError.StatusCode = response.statusCode
+	evervaultError.RequestID = response.header.Get("X-Request-Id")
+
+	if err := functionError(evervaultError); err != nil {
+		return err
+	}
+
+	switch status := response.statusCode; {
+	case status == http.StatusUnauthorized:
+		return AuthenticationError{evervaultError}
+	case status == http.StatusForbidden:
+		return ForbiddenError{evervaultError}
+	case status == http.StatusTooManyRequests:
+		return RateLimitError{APIError: evervaultError, RetryAfter: retryAfter(response.header, time.Now())}
+	case status == http.StatusBadRequest || status == http.StatusUnprocessableEntity:
+		return ValidationError{APIError: evervaultError, Fields: body.Errors}
+	case status >= http.StatusInternalServerError:
+		return ServerError{evervaultError}
+	default:
+		return evervaultError
+	}
+}
+
+// functionError converts API errors for Function runs into their specific error types, nil for other errors.
+func functionError(evervaultError APIError) error {
 	if evervaultError.Code == "functions/function-not-ready" {
-		functionNotReadyError := FunctionNotReadyError{Message: evervaultError.Message}
-		return functionNotReadyError
+		return FunctionNotReadyError{
+			Message:    evervaultError.Message,
+			StatusCode: evervaultError.StatusCode,
+			RequestID:  evervaultError.RequestID,
+		}
 	}
 
 	if evervaultError.Code == "functions/request-timeout" {
-		functionTimeoutError := FunctionTimeoutError{Message: evervaultError.Message}
-		return functionTimeoutError
+		return FunctionTimeoutError{
+			Message:    evervaultError.Message,
+			StatusCode: evervaultError.StatusCode,
+			RequestID:  evervaultError.RequestID,
+		}
 	}
 
-	return evervaultError
+	return nil
+}
+
+// retryAfter parses a Retry-After header given in seconds or as a HTTP date, zero if it is not set.
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+
+	return 0
 }
 
 // APIError represents an error returned from the Evervault API servers.
+// StatusCode and RequestID can be included in support requests to Evervault, RequestID is not set for errors
+// returned by ExtractAPIError. Use errors.Is with ErrNotFound, ErrUnauthorized, ErrForbidden or ErrRateLimited
+// to check the status, or errors.As with the typed errors such as AuthenticationError and RateLimitError.
 type APIError struct {
-	Code    string `json:"code"`
-	Message string `json:"detail"`
+	Code       string `json:"code"`
+	Message    string `json:"detail"`
+	StatusCode int    `json:"-"`
+	RequestID  string `json:"-"`
 }
 
 func (e APIError) Error() string {
 	return e.Message
 }
 
+// Is reports whether the status of the error matches ErrNotFound, ErrUnauthorized, ErrForbidden or ErrRateLimited.
+func (e APIError) Is(target error) bool {
+	switch target { //nolint:errorlint
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	default:
+		return false
+	}
+}
+
+// AuthenticationError is returned when the Evervault API cannot authenticate the request, for example because the
+// API key is invalid or has been revoked.
+type AuthenticationError struct {
+	APIError
+}
+
+func (e AuthenticationError) Unwrap() error {
+	return e.APIError
+}
+
+// ForbiddenError is returned when the API key is not permitted to perform the request, for example decrypting data
+// encrypted with a data role the API key is not allowed to decrypt.
+type ForbiddenError struct {
+	APIError
+}
+
+func (e ForbiddenError) Unwrap() error {
+	return e.APIError
+}
+
+// RateLimitError is returned when the App has exceeded the Evervault API rate limit. RetryAfter is how long to wait
+// before retrying, zero if the API did not say.
+type RateLimitError struct {
+	APIError
+	RetryAfter time.Duration
+}
+
+func (e RateLimitError) Unwrap() error {
+	return e.APIError
+}
+
+// FieldError describes a field of a request that failed validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when the Evervault API rejects a malformed request. Fields lists the invalid fields
+// if the API provided them.
+type ValidationError struct {
+	APIError
+	Fields []FieldError
+}
+
+func (e ValidationError) Unwrap() error {
+	return e.APIError
+}
+
+// ServerError is returned when the Evervault API fails to handle the request. These errors are usually temporary,
+// see Config.RetryPolicy to retry them.
+type ServerError struct {
+	APIError
+}
+
+func (e ServerError) Unwrap() error {
+	return e.APIError
+}
+
 // FunctionTimeoutError is returned when a function invocation times out.
 type FunctionTimeoutError struct {
-	Message string
+	Message    string
+	StatusCode int
+	RequestID  string
 }
 
 func (e FunctionTimeoutError) Error() string {
@@ -79,7 +282,9 @@ func (e FunctionTimeoutError) Error() string {
 // This can occur when it hasn't been executed in a while.
 // Retrying to run the Function after a short time should resolve this.
 type FunctionNotReadyError struct {
-	Message string
+	Message    string
+	StatusCode int
+	RequestID  string
 }
 
 func (e FunctionNotReadyError) Error() string {
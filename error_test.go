@@ -0,0 +1,207 @@
+//go:build unit_test
+// +build unit_test
+
+package evervault_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/evervault/evervault-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func errorServer(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+
+	mockServer := startMockHTTPServer("", "")
+	t.Cleanup(mockServer.Close)
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cages/key" {
+			mockServer.Config.Handler.ServeHTTP(writer, r)
+			return
+		}
+
+		writer.Header().Set("X-Request-Id", "req_123")
+		writer.WriteHeader(status)
+		writer.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestAPIErrorSentinels(t *testing.T) {
+	t.Parallel()
+
+	tests := map[int]error{
+		http.StatusNotFound:        evervault.ErrNotFound,
+		http.StatusUnauthorized:    evervault.ErrUnauthorized,
+		http.StatusTooManyRequests: evervault.ErrRateLimited,
+	}
+
+	for status, sentinel := range tests {
+		server := errorServer(t, status, `{"code": "some-code", "detail": "something went wrong"}`)
+		testClient := mockedClient(t, server)
+
+		_, err := testClient.CreateFunctionRunToken("test_function", "test_payload")
+		assert.ErrorIs(t, err, sentinel)
+
+		for _, other := range tests {
+			if other != sentinel {
+				assert.NotErrorIs(t, err, other)
+			}
+		}
+
+		var apiError evervault.APIError
+		if assert.True(t, errors.As(err, &apiError)) {
+			assert.Equal(t, "some-code", apiError.Code)
+			assert.Equal(t, "something went wrong", apiError.Message)
+			assert.Equal(t, status, apiError.StatusCode)
+			assert.Equal(t, "req_123", apiError.RequestID)
+		}
+	}
+}
+
+func TestAPIErrorWithoutBody(t *testing.T) {
+	t.Parallel()
+
+	server := errorServer(t, http.StatusBadGateway, "")
+	testClient := mockedClient(t, server)
+
+	_, err := testClient.DecryptString("ev:abc123")
+
+	var apiError evervault.APIError
+	if assert.ErrorAs(t, err, &apiError) {
+		assert.Equal(t, http.StatusBadGateway, apiError.StatusCode)
+		assert.Contains(t, apiError.Message, "502 Bad Gateway")
+	}
+}
+
+func TestNetworkErrorIsReturned(t *testing.T) {
+	t.Parallel()
+
+	server := startMockHTTPServer("", "")
+	testClient := mockedClient(t, server)
+	server.Close()
+
+	_, err := testClient.CreateFunctionRunToken("test_function", "test_payload")
+	assert.Error(t, err)
+
+	var apiError evervault.APIError
+	assert.False(t, errors.As(err, &apiError))
+}
+
+func TestTypedAPIErrors(t *testing.T) {
+	t.Parallel()
+
+	server := errorServer(t, http.StatusUnauthorized, `{"code": "unauthorized", "detail": "invalid api key"}`)
+	_, err := mockedClient(t, server).DecryptString("ev:abc123")
+
+	var authenticationError evervault.AuthenticationError
+	if assert.ErrorAs(t, err, &authenticationError) {
+		assert.Equal(t, "req_123", authenticationError.RequestID)
+		assert.Equal(t, "invalid api key", authenticationError.Error())
+	}
+
+	server = errorServer(t, http.StatusForbidden, `{"code": "forbidden", "detail": "data role not permitted"}`)
+	_, err = mockedClient(t, server).DecryptString("ev:abc123")
+
+	var forbiddenError evervault.ForbiddenError
+	assert.ErrorAs(t, err, &forbiddenError)
+	assert.ErrorIs(t, err, evervault.ErrForbidden)
+
+	server = errorServer(t, http.StatusUnprocessableEntity, `{"code": "invalid-request", "detail": "invalid payload",
+		"errors": [{"field": "payload.name", "message": "is required"}]}`)
+	_, err = mockedClient(t, server).CreateFunctionRunToken("test_function", "test_payload")
+
+	var validationError evervault.ValidationError
+	if assert.ErrorAs(t, err, &validationError) {
+		assert.Equal(t, []evervault.FieldError{{Field: "payload.name", Message: "is required"}}, validationError.Fields)
+	}
+
+	server = errorServer(t, http.StatusInternalServerError, `{"code": "internal-error", "detail": "internal error"}`)
+	_, err = mockedClient(t, server).CreateFunctionRunToken("test_function", "test_payload")
+
+	var serverError evervault.ServerError
+	if assert.ErrorAs(t, err, &serverError) {
+		assert.Equal(t, http.StatusInternalServerError, serverError.StatusCode)
+	}
+}
+
+func TestRateLimitErrorRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	mockServer := startMockHTTPServer("", "")
+	defer mockServer.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cages/key" {
+			mockServer.Config.Handler.ServeHTTP(writer, r)
+			return
+		}
+
+		writer.Header().Set("Retry-After", "30")
+		writer.WriteHeader(http.StatusTooManyRequests)
+		writer.Write([]byte(`{"code": "rate-limited", "detail": "too many requests"}`))
+	}))
+	defer server.Close()
+
+	_, err := mockedClient(t, server).DecryptString("ev:abc123")
+
+	var rateLimitError evervault.RateLimitError
+	if assert.ErrorAs(t, err, &rateLimitError) {
+		assert.Equal(t, 30*time.Second, rateLimitError.RetryAfter)
+	}
+
+	assert.ErrorIs(t, err, evervault.ErrRateLimited)
+}
+
+func TestFunctionErrorsIncludeRequestID(t *testing.T) {
+	t.Parallel()
+
+	server := errorServer(t, http.StatusConflict, `{"code": "functions/function-not-ready", "detail": "not ready"}`)
+	_, err := mockedClient(t, server).RunFunction("test_function", map[string]any{})
+
+	var notReadyError evervault.FunctionNotReadyError
+	if assert.ErrorAs(t, err, &notReadyError) {
+		assert.Equal(t, http.StatusConflict, notReadyError.StatusCode)
+		assert.Equal(t, "req_123", notReadyError.RequestID)
+	}
+}
+
+func TestExtractAPIError(t *testing.T) {
+	t.Parallel()
+
+	err := evervault.ExtractAPIError([]byte(`{"status": 403, "code": "forbidden", "detail": "data role not permitted"}`))
+
+	var forbiddenError evervault.ForbiddenError
+	if assert.ErrorAs(t, err, &forbiddenError) {
+		assert.Equal(t, "data role not permitted", forbiddenError.Message)
+		assert.Equal(t, http.StatusForbidden, forbiddenError.StatusCode)
+	}
+
+	err = evervault.ExtractAPIError([]byte(`{"status": 409, "code": "functions/function-not-ready",
+		"detail": "The Function is not ready to be invoked yet"}`))
+	assert.ErrorAs(t, err, &evervault.FunctionNotReadyError{})
+
+	err = evervault.ExtractAPIError([]byte(`{"status": 404, "code": "resource-not-found", "detail": "Not Found"}`))
+	assert.ErrorIs(t, err, evervault.ErrNotFound)
+	err = evervault.ExtractAPIError([]byte(`<html>Bad Gateway</html>`))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Error parsing JSON response")
+		assert.False(t, errors.As(err, &evervault.APIError{}))
+	}
+
+	err = evervault.ExtractAPIError([]byte(`{"code": "unknown", "detail": "Something went wrong"}`))
+
+	var apiError evervault.APIError
+	if assert.ErrorAs(t, err, &apiError) {
+		assert.Equal(t, "Something went wrong", apiError.Message)
+		assert.Zero(t, apiError.StatusCode)
+	}
+}
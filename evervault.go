@@ -11,6 +11,7 @@
 package evervault
 
 import (
+	"context"
 	"crypto/ecdh"
 	"crypto/rand"
 	"fmt"
@@ -37,13 +38,21 @@ func MakeClient(appUUID, apiKey string) (*Client, error) {
 // MakeCustomClient creates a new Client instance but can be specified with a Config. The client
 // will connect to Evervaults API to retrieve the public keys from your Evervault App.
 //
-// If an apiKey or appUUID is not passed then ErrAppCredentialsRequired is returned. If the client cannot
-// be created then nil will be returned.
+// Unset URLs, polling intervals and enclave timeouts in the Config are replaced by the Evervault defaults before it
+// is validated.
+//
+// If an apiKey or appUUID is not passed then ErrAppCredentialsRequired is returned. If the Config is invalid an
+// error wrapping ErrInvalidConfig is returned. If the client cannot be created then nil will be returned.
 func MakeCustomClient(appUUID, apiKey string, config Config) (*Client, error) {
 	if apiKey == "" || appUUID == "" {
 		return nil, ErrAppCredentialsRequired
 	}
 
+	config = config.withDefaults()
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
 	client := &Client{appUUID: appUUID, apiKey: apiKey, Config: config}
 	if err := client.initClient(); err != nil {
 		return nil, err
@@ -83,6 +92,31 @@ func (c *Client) getAesKeyAndCompressedEphemeralPublicKey() ([]byte, []byte, err
 	return aesKey, compressedEphemeralPublicKey, nil
 }
 
+// encrypt encrypts the string representation of a value with a fresh ephemeral key.
+func (c *Client) encrypt(value, role string, datatype datatypes.Datatype) (encrypted string, err error) {
+	_, span := c.tracer().Start(context.Background(), OperationEncrypt,
+		map[string]string{"datatype": datatypeName(datatype)})
+	defer func() { span.End(err) }()
+
+	aesKey, compressedEphemeralPublicKey, err := c.getAesKeyAndCompressedEphemeralPublicKey()
+	if err != nil {
+		return "", err
+	}
+
+	return crypto.EncryptValue(aesKey, compressedEphemeralPublicKey, c.p256PublicKeyCompressed, value, role, datatype)
+}
+
+func datatypeName(datatype datatypes.Datatype) string {
+	switch datatype {
+	case datatypes.Number:
+		return "number"
+	case datatypes.Boolean:
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
 // EncryptString encrypts the value passed to it using the Evervault Encryption Scheme.
 // The encrypted value is returned as an Evervault formatted encrypted string.
 //
@@ -103,13 +137,7 @@ func (c *Client) EncryptString(value string) (string, error) {
 // If an error occurs then nil is returned. If the error is due a problem with Key creation then
 // ErrCryptoKeyImportError is returned. For anyother error ErrCryptoUnableToPerformEncryption is returned.
 func (c *Client) EncryptStringWithDataRole(value, role string) (string, error) {
-	aesKey, compressedEphemeralPublicKey, err := c.getAesKeyAndCompressedEphemeralPublicKey()
-	if err != nil {
-		return "", err
-	}
-
-	return crypto.EncryptValue(aesKey, compressedEphemeralPublicKey, c.p256PublicKeyCompressed, value, role,
-		datatypes.String)
+	return c.encrypt(value, role, datatypes.String)
 }
 
 // EncryptInt encrypts the value passed to it using the Evervault Encryption Scheme.
@@ -132,15 +160,7 @@ func (c *Client) EncryptInt(value int) (string, error) {
 // If an error occurs then nil is returned. If the error is due a problem with Key creation then
 // ErrCryptoKeyImportError is returned. For anyother error ErrCryptoUnableToPerformEncryption is returned.
 func (c *Client) EncryptIntWithDataRole(value int, role string) (string, error) {
-	aesKey, compressedEphemeralPublicKey, err := c.getAesKeyAndCompressedEphemeralPublicKey()
-	if err != nil {
-		return "", err
-	}
-
-	val := strconv.Itoa(value)
-
-	return crypto.EncryptValue(aesKey, compressedEphemeralPublicKey, c.p256PublicKeyCompressed, val,
-		role, datatypes.Number)
+	return c.encrypt(strconv.Itoa(value), role, datatypes.Number)
 }
 
 // EncryptFloat64 encrypts the value passed to it using the Evervault Encryption Scheme.
@@ -163,15 +183,7 @@ func (c *Client) EncryptFloat64(value float64) (string, error) {
 // If an error occurs then nil is returned. If the error is due a problem with Key creation then
 // ErrCryptoKeyImportError is returned. For anyother error ErrCryptoUnableToPerformEncryption is returned.
 func (c *Client) EncryptFloat64WithDataRole(value float64, role string) (string, error) {
-	aesKey, compressedEphemeralPublicKey, err := c.getAesKeyAndCompressedEphemeralPublicKey()
-	if err != nil {
-		return "", err
-	}
-
-	val := strconv.FormatFloat(value, 'f', -1, 64)
-
-	return crypto.EncryptValue(aesKey, compressedEphemeralPublicKey, c.p256PublicKeyCompressed, val, role,
-		datatypes.Number)
+	return c.encrypt(strconv.FormatFloat(value, 'f', -1, 64), role, datatypes.Number)
 }
 
 // EncryptBool encrypts the value passed to it using the Evervault Encryption Scheme.
@@ -194,15 +206,7 @@ func (c *Client) EncryptBool(value bool) (string, error) {
 // If an error occurs then nil is returned. If the error is due a problem with Key creation then
 // ErrCryptoKeyImportError is returned. For anyother error ErrCryptoUnableToPerformEncryption is returned.
 func (c *Client) EncryptBoolWithDataRole(value bool, role string) (string, error) {
-	aesKey, compressedEphemeralPublicKey, err := c.getAesKeyAndCompressedEphemeralPublicKey()
-	if err != nil {
-		return "", err
-	}
-
-	val := strconv.FormatBool(value)
-
-	return crypto.EncryptValue(aesKey, compressedEphemeralPublicKey, c.p256PublicKeyCompressed, val, role,
-		datatypes.Boolean)
+	return c.encrypt(strconv.FormatBool(value), role, datatypes.Boolean)
 }
 
 // EncryptByteArray encrypts the value passed to it using the Evervault Encryption Scheme.
@@ -229,15 +233,7 @@ func (c *Client) EncryptByteArray(value []byte) (string, error) {
 //
 // Deprecated: Use EncryptString for utf-8 encoded byte arrays.
 func (c *Client) EncryptByteArrayWithDataRole(value []byte, role string) (string, error) {
-	aesKey, compressedEphemeralPublicKey, err := c.getAesKeyAndCompressedEphemeralPublicKey()
-	if err != nil {
-		return "", err
-	}
-
-	val := string(value)
-
-	return crypto.EncryptValue(aesKey, compressedEphemeralPublicKey, c.p256PublicKeyCompressed, val, role,
-		datatypes.String)
+	return c.encrypt(string(value), role, datatypes.String)
 }
 
 // DecryptString decrypts data previously encrypted with Encrypt or through Relay
@@ -336,6 +332,8 @@ func (c *Client) DecryptByteArray(encryptedData string) ([]byte, error) {
 // # It returns a TokenResponse or an error
 //
 // token, err := CreateClientSideDecryptToken(payload, timeInFiveMinutes).
+//
+// Only the first expiry is used. CreateToken supports other actions, several payloads and validates the expiry.
 func (c *Client) CreateClientSideDecryptToken(payload any, expiry ...time.Time) (TokenResponse, error) {
 	// Used to check whether payload is the zero value for its type
 	if payload == nil {
@@ -347,7 +345,7 @@ func (c *Client) CreateClientSideDecryptToken(payload any, expiry ...time.Time)
 		epochTime = expiry[0].UnixMilli()
 	}
 
-	token, err := c.createToken("api:decrypt", payload, epochTime)
+	token, err := c.createToken(TokenActionDecrypt, payload, epochTime)
 	if err != nil {
 		return TokenResponse{}, err
 	}
@@ -5,14 +5,21 @@ package evervault_test
 
 import (
 	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"log"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"sync"
 	"time"
 
 	"strings"
@@ -469,6 +476,11 @@ func handleRoute(writer http.ResponseWriter, reader *http.Request, mockResponse
 		return
 	}
 
+	if reader.URL.Path == "/ca.crt" {
+		writer.Write(testCACert())
+		return
+	}
+
 	if reader.URL.Path == "/client-side-tokens" {
 		if contentType == "" {
 			contentType = "application/json"
@@ -494,12 +506,46 @@ func handleRoute(writer http.ResponseWriter, reader *http.Request, mockResponse
 	}
 }
 
+var (
+	testCACertOnce sync.Once
+	testCACertPEM  []byte
+)
+
+// testCACert returns a self-signed PEM encoded certificate to serve as the Evervault CA.
+func testCACert() []byte {
+	testCACertOnce.Do(func() {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			log.Fatalf("error generating CA key: %s", err)
+		}
+
+		template := &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: "Test Evervault CA"},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(time.Hour),
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+		}
+
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		if err != nil {
+			log.Fatalf("error creating CA certificate: %s", err)
+		}
+
+		testCACertPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	})
+
+	return testCACertPEM
+}
+
 func hasSpecialPath(path string) bool {
 	specialPaths := map[string]bool{
 		"/functions/test_function/runs":         true,
 		"/v2/functions/test_function/run-token": true,
 		"/decrypt":                              true,
 		"/client-side-tokens":                   true,
+		"/ca.crt":                               true,
 	}
 
 	return specialPaths[path]
@@ -543,7 +589,7 @@ func mockedClient(t *testing.T, server *httptest.Server) *evervault.Client {
 	t.Helper()
 
 	config := evervault.Config{
-		EvervaultCaURL: server.URL,
+		EvervaultCaURL: server.URL + "/ca.crt",
 		EvAPIURL:       server.URL,
 		RelayURL:       server.URL,
 	}
@@ -0,0 +1,246 @@
+package evervaulttest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/evervault/evervault-go/attestation"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Values of an attestation doc that are fixed for every doc issued.
+const (
+	moduleID        = "i-evervaulttest-enc00000000000000"
+	pcrSize         = sha512.Size384
+	coseAlgES384    = -35
+	es384ScalarSize = 48
+	certificateLife = 24 * time.Hour
+)
+
+// document is the CBOR payload of a Nitro Enclaves attestation doc.
+type document struct {
+	ModuleID    string          `cbor:"module_id"`
+	Digest      string          `cbor:"digest"`
+	Timestamp   uint64          `cbor:"timestamp"`
+	PCRs        map[uint][]byte `cbor:"pcrs"`
+	Certificate []byte          `cbor:"certificate"`
+	CABundle    [][]byte        `cbor:"cabundle"`
+	PublicKey   []byte          `cbor:"public_key,omitempty"`
+	UserData    []byte          `cbor:"user_data,omitempty"`
+	Nonce       []byte          `cbor:"nonce,omitempty"`
+}
+
+// coseHeader is the protected header of an attestation doc.
+type coseHeader struct {
+	Alg int `cbor:"1,keyasint"`
+}
+
+// coseSign1 is a COSE_Sign1 message (RFC 8152 section 4.2).
+type coseSign1 struct {
+	_           struct{} `cbor:",toarray"`
+	Protected   []byte
+	Unprotected map[int]any
+	Payload     []byte
+	Signature   []byte
+}
+
+// sigStructure is the Sig_structure signed for a COSE_Sign1 message (RFC 8152 section 4.4).
+type sigStructure struct {
+	_           struct{} `cbor:",toarray"`
+	Context     string
+	Protected   []byte
+	ExternalAAD []byte
+	Payload     []byte
+}
+
+// AttestationDoc describes a synthetic Nitro Enclaves attestation doc issued by an AttestationRoot.
+type AttestationDoc struct {
+	PCRs      attestation.PCRs // PCR0, PCR1, PCR2 and PCR8 of the doc, empty PCRs are zero.
+	UserData  []byte           // User data of the doc, the public key of the enclave TLS certificate when attesting.
+	Nonce     []byte           // Nonce of the doc, set to the challenge when a doc is requested with one.
+	PublicKey []byte           // Public key of the doc.
+	Timestamp time.Time        // Time the doc was issued, now if zero.
+}
+
+// AttestationRoot is a self-signed root that issues attestation docs in the format of AWS Nitro Enclaves, as
+// COSE_Sign1 structures signed with a certificate issued by the root. Connections are attested against the docs
+// when the root is trusted with evervault.WithAttestationRoots.
+type AttestationRoot struct {
+	root        *x509.Certificate
+	signingCert *x509.Certificate
+	signingKey  *ecdsa.PrivateKey
+}
+
+// NewAttestationRoot generates a root and the certificate it signs attestation docs with.
+func NewAttestationRoot() (*AttestationRoot, error) {
+	rootKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("error generating root key %w", err)
+	}
+
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "evervaulttest Nitro Enclaves root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(certificateLife),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		SignatureAlgorithm:    x509.ECDSAWithSHA384,
+	}
+
+	root, err := createCertificate(rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		return nil, err
+	}
+
+	signingKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("error generating signing key %w", err)
+	}
+
+	signingTemplate := &x509.Certificate{
+		SerialNumber:       big.NewInt(2),
+		Subject:            pkix.Name{CommonName: moduleID},
+		NotBefore:          time.Now().Add(-time.Hour),
+		NotAfter:           time.Now().Add(certificateLife),
+		KeyUsage:           x509.KeyUsageDigitalSignature,
+		SignatureAlgorithm: x509.ECDSAWithSHA384,
+	}
+
+	signingCert, err := createCertificate(signingTemplate, root, &signingKey.PublicKey, rootKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AttestationRoot{root: root, signingCert: signingCert, signingKey: signingKey}, nil
+}
+
+// CertPool returns a pool with the root, to pass to evervault.WithAttestationRoots.
+func (r *AttestationRoot) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(r.root)
+
+	return pool
+}
+
+// IssueDoc returns a CBOR encoded COSE_Sign1 attestation doc with the values of doc, signed by the root.
+func (r *AttestationRoot) IssueDoc(doc AttestationDoc) ([]byte, error) {
+	pcrs, err := encodePCRs(doc.PCRs)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := doc.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	payload, err := cbor.Marshal(document{
+		ModuleID: moduleID,
+		Digest:   "SHA384",
+		//nolint:gosec
+		Timestamp:   uint64(timestamp.UnixMilli()),
+		PCRs:        pcrs,
+		Certificate: r.signingCert.Raw,
+		CABundle:    [][]byte{r.root.Raw},
+		PublicKey:   doc.PublicKey,
+		UserData:    doc.UserData,
+		Nonce:       doc.Nonce,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding attestation doc %w", err)
+	}
+
+	protected, err := cbor.Marshal(coseHeader{Alg: coseAlgES384})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding attestation doc header %w", err)
+	}
+
+	toSign, err := cbor.Marshal(sigStructure{
+		Context:     "Signature1",
+		Protected:   protected,
+		ExternalAAD: []byte{},
+		Payload:     payload,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding attestation doc signature structure %w", err)
+	}
+
+	digest := sha512.Sum384(toSign)
+
+	sigR, sigS, err := ecdsa.Sign(rand.Reader, r.signingKey, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("error signing attestation doc %w", err)
+	}
+
+	signature := make([]byte, 2*es384ScalarSize)
+	sigR.FillBytes(signature[:es384ScalarSize])
+	sigS.FillBytes(signature[es384ScalarSize:])
+
+	issued, err := cbor.Marshal(coseSign1{
+		Protected:   protected,
+		Unprotected: map[int]any{},
+		Payload:     payload,
+		Signature:   signature,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding attestation doc %w", err)
+	}
+
+	return issued, nil
+}
+
+// encodePCRs returns PCR0, PCR1, PCR2 and PCR8 as a map of index to measurement.
+func encodePCRs(pcrs attestation.PCRs) (map[uint][]byte, error) {
+	indexed := []struct {
+		index uint
+		value string
+	}{
+		{0, pcrs.PCR0},
+		{1, pcrs.PCR1},
+		{2, pcrs.PCR2},
+		{8, pcrs.PCR8},
+	}
+
+	encoded := make(map[uint][]byte, len(indexed))
+
+	for _, pcr := range indexed {
+		measurement := make([]byte, pcrSize)
+
+		if pcr.value != "" {
+			decoded, err := hex.DecodeString(pcr.value)
+			if err != nil || len(decoded) != pcrSize {
+				return nil, fmt.Errorf("PCR%d: %w", pcr.index, attestation.ErrMalformedPCR)
+			}
+
+			measurement = decoded
+		}
+
+		encoded[pcr.index] = measurement
+	}
+
+	return encoded, nil
+}
+
+func createCertificate(template, parent *x509.Certificate, publicKey any, signer any) (*x509.Certificate, error) {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, publicKey, signer)
+	if err != nil {
+		return nil, fmt.Errorf("error creating certificate %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing certificate %w", err)
+	}
+
+	return cert, nil
+}
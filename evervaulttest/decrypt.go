@@ -0,0 +1,302 @@
+package evervaulttest
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evervault/evervault-go/internal/crypto"
+	"github.com/evervault/evervault-go/internal/datatypes"
+)
+
+// Client-side token expiry defaults and limits of the Evervault API, and the length of the metadata length prefix
+// of encrypted values.
+const (
+	defaultTokenExpiry   = 5 * time.Minute
+	maxTokenExpiry       = 10 * time.Minute
+	metadataOffsetLength = 2
+)
+
+// errNotDecryptable is returned for encrypted strings the Server has no value for and cannot decrypt.
+var errNotDecryptable = errors.New("unable to decrypt data")
+
+// clientSideToken is a token issued by /client-side-tokens.
+type clientSideToken struct {
+	action  string
+	payload json.RawMessage
+	expiry  time.Time
+}
+
+// tokenRequest is the body of a request to create a client-side token.
+type tokenRequest struct {
+	Action  string          `json:"action"`
+	Payload json.RawMessage `json:"payload"`
+	Expiry  int64           `json:"expiry"`
+}
+
+// AddDecrypted registers the value returned when the encrypted string is decrypted through the Server, for values
+// that were not encrypted with the key of the Server such as fixtures encrypted by another App.
+func (s *Server) AddDecrypted(encrypted string, value any) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.decrypted[encrypted] = value
+}
+
+// Decrypt decrypts an encrypted string with the App private key of the Server, returning a string, float64 or bool
+// depending on the type of the encrypted value. Values registered with AddDecrypted are returned as registered.
+func (s *Server) Decrypt(encrypted string) (any, error) {
+	s.mutex.Lock()
+	value, ok := s.decrypted[encrypted]
+	s.mutex.Unlock()
+
+	if ok {
+		return value, nil
+	}
+
+	plaintext, datatype, err := decryptValue(s.key, encrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	switch datatype {
+	case datatypes.Number:
+		number, err := strconv.ParseFloat(plaintext, 64)
+		if err != nil {
+			return nil, errNotDecryptable
+		}
+
+		return number, nil
+	case datatypes.Boolean:
+		boolean, err := strconv.ParseBool(plaintext)
+		if err != nil {
+			return nil, errNotDecryptable
+		}
+
+		return boolean, nil
+	default:
+		return plaintext, nil
+	}
+}
+
+// decryptValue decrypts an encrypted string created by the Client with the App private key, returning the
+// plaintext value and its datatype.
+func decryptValue(appPrivateKey *ecdh.PrivateKey, encrypted string) (string, datatypes.Datatype, error) {
+	parts := strings.Split(encrypted, ":")
+	if (len(parts) != 6 && len(parts) != 7) || parts[0] != "ev" || parts[len(parts)-1] != "$" {
+		return "", datatypes.String, errNotDecryptable
+	}
+
+	datatype := datatypes.Datatype(datatypes.String)
+
+	if len(parts) == 7 {
+		switch parts[2] {
+		case "number":
+			datatype = datatypes.Number
+		case "boolean":
+			datatype = datatypes.Boolean
+		default:
+			return "", datatypes.String, errNotDecryptable
+		}
+	}
+
+	fields := make([][]byte, 3)
+
+	for i, part := range parts[len(parts)-4 : len(parts)-1] {
+		decoded, err := base64.RawStdEncoding.DecodeString(part)
+		if err != nil {
+			return "", datatypes.String, fmt.Errorf("%w: %w", errNotDecryptable, err)
+		}
+
+		fields[i] = decoded
+	}
+
+	nonce, compressedEphemeralPublicKey, ciphertext := fields[0], fields[1], fields[2]
+
+	ephemeralPublicKeyBytes, err := crypto.DecompressPublicKey(compressedEphemeralPublicKey)
+	if err != nil {
+		return "", datatypes.String, fmt.Errorf("%w: %w", errNotDecryptable, err)
+	}
+
+	ephemeralPublicKey, err := ecdh.P256().NewPublicKey(ephemeralPublicKeyBytes)
+	if err != nil {
+		return "", datatypes.String, fmt.Errorf("%w: %w", errNotDecryptable, err)
+	}
+
+	shared, err := appPrivateKey.ECDH(ephemeralPublicKey)
+	if err != nil {
+		return "", datatypes.String, fmt.Errorf("error deriving shared secret %w", err)
+	}
+
+	aesKey, err := crypto.DeriveKDFAESKey(ephemeralPublicKeyBytes, shared)
+	if err != nil {
+		return "", datatypes.String, fmt.Errorf("error deriving aes key %w", err)
+	}
+
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return "", datatypes.String, fmt.Errorf("unable to create cipher %w", err)
+	}
+
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil || len(nonce) != aesgcm.NonceSize() {
+		return "", datatypes.String, errNotDecryptable
+	}
+
+	v2Aad, err := crypto.CreateV2Aad(datatype, compressedEphemeralPublicKey,
+		crypto.CompressPublicKey(appPrivateKey.PublicKey().Bytes()))
+	if err != nil {
+		return "", datatypes.String, fmt.Errorf("unable to create v2 aad %w", err)
+	}
+
+	valueWithMetadata, err := aesgcm.Open(nil, nonce, ciphertext, v2Aad.Bytes())
+	if err != nil {
+		return "", datatypes.String, fmt.Errorf("%w: %w", errNotDecryptable, err)
+	}
+
+	if len(valueWithMetadata) < metadataOffsetLength {
+		return "", datatypes.String, errNotDecryptable
+	}
+
+	metadataEnd := metadataOffsetLength + int(binary.LittleEndian.Uint16(valueWithMetadata))
+	if metadataEnd > len(valueWithMetadata) {
+		return "", datatypes.String, errNotDecryptable
+	}
+
+	return string(valueWithMetadata[metadataEnd:]), datatype, nil
+}
+
+// decryptJSON decrypts every encrypted string in a decoded JSON document.
+func (s *Server) decryptJSON(value any) (any, error) {
+	switch typed := value.(type) {
+	case string:
+		if !strings.HasPrefix(typed, "ev:") {
+			return typed, nil
+		}
+
+		return s.Decrypt(typed)
+	case []any:
+		for i, item := range typed {
+			decrypted, err := s.decryptJSON(item)
+			if err != nil {
+				return nil, err
+			}
+
+			typed[i] = decrypted
+		}
+	case map[string]any:
+		for key, item := range typed {
+			decrypted, err := s.decryptJSON(item)
+			if err != nil {
+				return nil, err
+			}
+
+			typed[key] = decrypted
+		}
+	}
+
+	return value, nil
+}
+
+// handleDecrypt decrypts the encrypted strings in any JSON document, as /decrypt of the Evervault API. Requests
+// are authorized with the Server credentials or a client-side token for the document as a Bearer token.
+func (s *Server) handleDecrypt(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writeError(writer, http.StatusMethodNotAllowed, "method-not-allowed", "Method Not Allowed")
+		return
+	}
+
+	var body any
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		writeError(writer, http.StatusBadRequest, "invalid-request", "Request body must be JSON")
+		return
+	}
+
+	if token, ok := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer "); ok {
+		if !s.tokenAllows(token, "api:decrypt", body) {
+			writeError(writer, http.StatusForbidden, "forbidden", "Token is expired or not valid for this payload")
+			return
+		}
+	} else if !authorized(request) {
+		writeError(writer, http.StatusUnauthorized, "unauthorized", "Invalid API key")
+		return
+	}
+
+	decrypted, err := s.decryptJSON(body)
+	if err != nil {
+		writeError(writer, http.StatusUnprocessableEntity, "decryption-failed", "Unable to decrypt data")
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, decrypted)
+}
+
+// tokenAllows reports whether a client-side token is unexpired and was issued for the action on the payload.
+func (s *Server) tokenAllows(token, action string, payload any) bool {
+	s.mutex.Lock()
+	issued, ok := s.tokens[token]
+	s.mutex.Unlock()
+
+	if !ok || issued.action != action || !time.Now().Before(issued.expiry) {
+		return false
+	}
+
+	var issuedPayload any
+	if err := json.Unmarshal(issued.payload, &issuedPayload); err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(issuedPayload, payload)
+}
+
+// handleClientSideToken issues tokens for a payload, as /client-side-tokens of the Evervault API.
+func (s *Server) handleClientSideToken(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writeError(writer, http.StatusMethodNotAllowed, "method-not-allowed", "Method Not Allowed")
+		return
+	}
+
+	if !authorized(request) {
+		writeError(writer, http.StatusUnauthorized, "unauthorized", "Invalid API key")
+		return
+	}
+
+	var body tokenRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil || body.Action == "" ||
+		len(body.Payload) == 0 || string(body.Payload) == "null" {
+		writeError(writer, http.StatusBadRequest, "invalid-request", "Request body must have an action and payload")
+		return
+	}
+
+	now := time.Now()
+	expiry := now.Add(defaultTokenExpiry)
+
+	if body.Expiry != 0 {
+		expiry = time.UnixMilli(body.Expiry)
+	}
+
+	if !expiry.After(now) || expiry.After(now.Add(maxTokenExpiry)) {
+		writeError(writer, http.StatusUnprocessableEntity, "invalid-request",
+			"Expiry must be in the future and at most 10 minutes from now")
+
+		return
+	}
+
+	token := newID("ev_token_")
+
+	s.mutex.Lock()
+	s.tokens[token] = clientSideToken{action: body.Action, payload: body.Payload, expiry: expiry}
+	s.mutex.Unlock()
+
+	writeJSON(writer, http.StatusOK, map[string]any{"token": token, "expiry": expiry.UnixMilli()})
+}
@@ -0,0 +1,179 @@
+package evervaulttest
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evervault/evervault-go"
+	"github.com/evervault/evervault-go/attestation"
+)
+
+// Enclave is a TLS server that serves attestation docs issued by its own AttestationRoot at
+// /.well-known/attestation, so the enclave client of evervault.Client can be tested without a Nitro Enclave.
+//
+//	enclave := evervaulttest.NewEnclave(pcrs, handler)
+//	defer enclave.Close()
+//
+//	enclaveClient, err := evClient.EnclaveClient(enclave.Hostname, []attestation.PCRs{pcrs}, enclave.Options()...)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	resp, err := enclaveClient.Get(enclave.URL + "/hello")
+type Enclave struct {
+	// URL of the Enclave, in the form https://127.0.0.1:port.
+	URL string
+	// Hostname of the Enclave including the port, to pass to the enclave client of evervault.Client.
+	Hostname string
+
+	server    *httptest.Server
+	root      *AttestationRoot
+	tlsKey    []byte
+	mutex     sync.Mutex
+	pcrs      attestation.PCRs
+	userData  []byte
+	nonce     []byte
+	timestamp time.Time
+}
+
+// NewEnclave starts an Enclave that issues attestation docs with the PCRs and passes every other request to
+// handler. A nil handler responds to other requests with 404 Not Found. It must be closed with Close when no
+// longer needed.
+func NewEnclave(pcrs attestation.PCRs, handler http.Handler) *Enclave {
+	root, err := NewAttestationRoot()
+	if err != nil {
+		panic(fmt.Sprintf("evervaulttest: error generating attestation root %s", err))
+	}
+
+	if handler == nil {
+		handler = http.NotFoundHandler()
+	}
+
+	enclave := &Enclave{root: root, pcrs: pcrs}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/attestation", enclave.handleAttestation)
+	mux.Handle("/", handler)
+
+	enclave.server = httptest.NewTLSServer(mux)
+	enclave.URL = enclave.server.URL
+	enclave.Hostname = strings.TrimPrefix(enclave.server.URL, "https://")
+
+	enclave.tlsKey, err = x509.MarshalPKIXPublicKey(enclave.server.Certificate().PublicKey)
+	if err != nil {
+		enclave.server.Close()
+		panic(fmt.Sprintf("evervaulttest: error encoding TLS public key %s", err))
+	}
+
+	return enclave
+}
+
+// Close shuts down the Enclave.
+func (e *Enclave) Close() {
+	e.server.Close()
+}
+
+// Options returns the evervault.EnclaveOption values that trust the attestation root and TLS certificate of the
+// Enclave.
+func (e *Enclave) Options() []evervault.EnclaveOption {
+	return []evervault.EnclaveOption{
+		evervault.WithAttestationRoots(e.AttestationRoots()),
+		evervault.WithEnclaveRootCAs(e.RootCAs()),
+	}
+}
+
+// AttestationRoots returns a pool with the root the attestation docs of the Enclave are signed by.
+func (e *Enclave) AttestationRoots() *x509.CertPool {
+	return e.root.CertPool()
+}
+
+// RootCAs returns a pool with the TLS certificate of the Enclave.
+func (e *Enclave) RootCAs() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(e.server.Certificate())
+
+	return pool
+}
+
+// SetPCRs changes the PCRs of the attestation docs issued after it is called.
+func (e *Enclave) SetPCRs(pcrs attestation.PCRs) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.pcrs = pcrs
+}
+
+// SetUserData changes the user data of the attestation docs issued after it is called. By default the user data is
+// the public key of the TLS certificate of the Enclave, which connections are attested against. Nil restores the
+// default.
+func (e *Enclave) SetUserData(userData []byte) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.userData = userData
+}
+
+// SetNonce changes the nonce of the attestation docs issued after it is called, instead of the challenge the doc
+// is requested with. Nil restores the default.
+func (e *Enclave) SetNonce(nonce []byte) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.nonce = nonce
+}
+
+// SetTimestamp changes the time the attestation docs issued after it is called claim to be issued at. The zero
+// time restores the default of the time the doc is requested.
+func (e *Enclave) SetTimestamp(timestamp time.Time) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.timestamp = timestamp
+}
+
+// handleAttestation issues an attestation doc, bound to the nonce query parameter if it is set.
+func (e *Enclave) handleAttestation(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		writeError(writer, http.StatusMethodNotAllowed, "method-not-allowed", "Method Not Allowed")
+		return
+	}
+
+	var nonce []byte
+
+	if value := request.URL.Query().Get("nonce"); value != "" {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			writeError(writer, http.StatusBadRequest, "invalid-request", "Nonce must be base64 encoded")
+			return
+		}
+
+		nonce = decoded
+	}
+
+	e.mutex.Lock()
+	doc := AttestationDoc{PCRs: e.pcrs, UserData: e.userData, Nonce: nonce, Timestamp: e.timestamp}
+	fixedNonce := e.nonce
+	e.mutex.Unlock()
+
+	if fixedNonce != nil {
+		doc.Nonce = fixedNonce
+	}
+
+	if doc.UserData == nil {
+		doc.UserData = e.tlsKey
+	}
+
+	issued, err := e.root.IssueDoc(doc)
+	if err != nil {
+		writeError(writer, http.StatusInternalServerError, "internal-error", err.Error())
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, map[string]string{"attestation_doc": base64.StdEncoding.EncodeToString(issued)})
+}
@@ -0,0 +1,563 @@
+//go:build unit_test
+// +build unit_test
+
+package evervaulttest_test
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/evervault/evervault-go"
+	"github.com/evervault/evervault-go/attestation"
+	"github.com/evervault/evervault-go/evervaulttest"
+	"github.com/hf/nitrite"
+	"github.com/stretchr/testify/assert"
+)
+
+// singleAttestationAttempt makes attestation doc fetches fail without waiting for retries.
+func singleAttestationAttempt(config *evervault.Config) error {
+	config.AttestationRetryPolicy = evervault.RetryPolicy{MaxAttempts: 1}
+
+	return nil
+}
+
+type recordingLogger struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+func (l *recordingLogger) record(msg string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.messages = append(l.messages, msg)
+}
+
+func (l *recordingLogger) logged(msg string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for _, message := range l.messages {
+		if message == msg {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (l *recordingLogger) Debug(msg string, _ ...any) { l.record(msg) }
+func (l *recordingLogger) Info(msg string, _ ...any)  { l.record(msg) }
+func (l *recordingLogger) Warn(msg string, _ ...any)  { l.record(msg) }
+func (l *recordingLogger) Error(msg string, _ ...any) { l.record(msg) }
+
+type recordingInstrumentation struct {
+	mutex      sync.Mutex
+	operations []string
+}
+
+func (i *recordingInstrumentation) Start(
+	ctx context.Context, operation string, _ map[string]string,
+) (context.Context, evervault.Span) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.operations = append(i.operations, operation)
+
+	return ctx, noopSpan{}
+}
+
+func (i *recordingInstrumentation) started(operation string) bool {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	for _, started := range i.operations {
+		if started == operation {
+			return true
+		}
+	}
+
+	return false
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, string) {}
+func (noopSpan) End(error)                   {}
+
+var enclavePCRs = attestation.PCRs{
+	PCR0: strings.Repeat("0a", 48),
+	PCR1: strings.Repeat("1b", 48),
+	PCR2: strings.Repeat("2c", 48),
+	PCR8: strings.Repeat("8d", 48),
+}
+
+func TestAttestationRootIssuesNitroDoc(t *testing.T) {
+	t.Parallel()
+
+	root, err := evervaulttest.NewAttestationRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := root.IssueDoc(evervaulttest.AttestationDoc{
+		PCRs:     enclavePCRs,
+		UserData: []byte("user data"),
+		Nonce:    []byte("nonce"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := nitrite.Verify(doc, nitrite.VerifyOptions{Roots: root.CertPool()})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.True(t, result.SignatureOK)
+	assert.Equal(t, []byte("user data"), result.Document.UserData)
+	assert.Equal(t, []byte("nonce"), result.Document.Nonce)
+	assert.Equal(t, strings.Repeat("\x8d", 48), string(result.Document.PCRs[8]))
+
+	_, err = nitrite.Verify(doc, nitrite.VerifyOptions{})
+	assert.Error(t, err)
+
+	_, err = root.IssueDoc(evervaulttest.AttestationDoc{PCRs: attestation.PCRs{PCR0: "not hex"}})
+	assert.ErrorIs(t, err, attestation.ErrMalformedPCR)
+}
+
+func startEnclave(t *testing.T, opts ...evervault.Option) (*evervaulttest.Enclave, *evervault.Client) {
+	t.Helper()
+
+	enclave := evervaulttest.NewEnclave(enclavePCRs, http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Write([]byte("hello from the enclave"))
+	}))
+	t.Cleanup(enclave.Close)
+
+	server := evervaulttest.NewServer()
+	t.Cleanup(server.Close)
+
+	client, err := server.Client(append([]evervault.Option{evervault.WithLogger(evervault.DiscardLogger)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { client.Close() })
+
+	return enclave, client
+}
+
+func getEnclave(t *testing.T, client *http.Client, url string) (string, error) {
+	t.Helper()
+
+	response, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+
+	return string(body), err
+}
+
+func TestEnclaveAttestsConnection(t *testing.T) {
+	t.Parallel()
+
+	enclave, client := startEnclave(t)
+
+	enclaveClient, err := client.EnclaveClient(enclave.Hostname, []attestation.PCRs{enclavePCRs}, enclave.Options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := getEnclave(t, enclaveClient, enclave.URL+"/hello")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello from the enclave", body)
+}
+
+func TestEnclaveAttestsChallenge(t *testing.T) {
+	t.Parallel()
+
+	enclave, client := startEnclave(t)
+
+	opts := append(enclave.Options(), evervault.WithAttestationChallenge())
+
+	enclaveClient, err := client.EnclaveClient(enclave.Hostname, []attestation.PCRs{enclavePCRs}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := getEnclave(t, enclaveClient, enclave.URL+"/hello")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello from the enclave", body)
+}
+
+func TestEnclaveRejectsMismatchedPCRs(t *testing.T) {
+	t.Parallel()
+
+	enclave, client := startEnclave(t)
+
+	otherPCRs := enclavePCRs
+	otherPCRs.PCR8 = strings.Repeat("ff", 48)
+
+	enclaveClient, err := client.EnclaveClient(enclave.Hostname, []attestation.PCRs{otherPCRs}, enclave.Options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = getEnclave(t, enclaveClient, enclave.URL+"/hello")
+	assert.ErrorIs(t, err, evervault.ErrAttestionFailure)
+}
+
+func TestEnclaveRejectsMismatchedUserData(t *testing.T) {
+	t.Parallel()
+
+	enclave, client := startEnclave(t)
+	enclave.SetUserData([]byte("not the TLS public key"))
+
+	opts := append(enclave.Options(), evervault.WithAttestationChallenge())
+
+	enclaveClient, err := client.EnclaveClient(enclave.Hostname, []attestation.PCRs{enclavePCRs}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = getEnclave(t, enclaveClient, enclave.URL+"/hello")
+	assert.ErrorIs(t, err, evervault.ErrAttestionFailure)
+}
+
+func TestEnclaveRejectsStaleDoc(t *testing.T) {
+	t.Parallel()
+
+	enclave, client := startEnclave(t)
+	enclave.SetTimestamp(time.Now().Add(-time.Hour))
+
+	maxAge := evervault.WithMaxAttestationDocAge(time.Minute)
+
+	for name, opts := range map[string][]evervault.EnclaveOption{
+		"cached":    append(enclave.Options(), maxAge),
+		"challenge": append(enclave.Options(), maxAge, evervault.WithAttestationChallenge()),
+	} {
+		enclaveClient, err := client.EnclaveClient(enclave.Hostname, []attestation.PCRs{enclavePCRs}, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = getEnclave(t, enclaveClient, enclave.URL+"/hello")
+		assert.ErrorIs(t, err, evervault.ErrStaleAttestationDoc, name)
+	}
+
+	enclave.SetTimestamp(time.Time{})
+
+	enclaveClient, err := client.EnclaveClient(enclave.Hostname, []attestation.PCRs{enclavePCRs},
+		append(enclave.Options(), maxAge)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := getEnclave(t, enclaveClient, enclave.URL+"/hello")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello from the enclave", body)
+}
+
+func TestEnclaveRejectsMismatchedNonce(t *testing.T) {
+	t.Parallel()
+
+	enclave, client := startEnclave(t)
+	enclave.SetNonce([]byte("not the challenge"))
+
+	opts := append(enclave.Options(), evervault.WithAttestationChallenge())
+
+	enclaveClient, err := client.EnclaveClient(enclave.Hostname, []attestation.PCRs{enclavePCRs}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = getEnclave(t, enclaveClient, enclave.URL+"/hello")
+	assert.ErrorIs(t, err, evervault.ErrAttestationNonceMismatch)
+}
+
+func TestEnclaveRootCAsAreNotShared(t *testing.T) {
+	t.Parallel()
+
+	enclave := evervaulttest.NewEnclave(enclavePCRs, http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Write([]byte("hello from the enclave"))
+	}))
+	defer enclave.Close()
+
+	server := evervaulttest.NewServer()
+	defer server.Close()
+
+	var clients [2]*evervault.Client
+
+	for i := range clients {
+		client, err := server.Client(evervault.WithLogger(evervault.DiscardLogger), singleAttestationAttempt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer client.Close()
+
+		clients[i] = client
+	}
+
+	// The first client does not trust the TLS certificate of the enclave, so it cannot fetch attestation docs.
+	untrusted, err := clients[0].EnclaveClient(enclave.Hostname, []attestation.PCRs{enclavePCRs},
+		evervault.WithAttestationRoots(enclave.AttestationRoots()), evervault.WithEnclaveRootCAs(x509.NewCertPool()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trusted, err := clients[1].EnclaveClient(enclave.Hostname, []attestation.PCRs{enclavePCRs}, enclave.Options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := getEnclave(t, trusted, enclave.URL+"/hello")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello from the enclave", body)
+
+	_, err = getEnclave(t, untrusted, enclave.URL+"/hello")
+	assert.Error(t, err)
+}
+
+func TestEnclaveRequiresAttestationRoot(t *testing.T) {
+	t.Parallel()
+
+	enclave, client := startEnclave(t)
+
+	opts := []evervault.EnclaveOption{
+		evervault.WithEnclaveRootCAs(enclave.RootCAs()),
+		evervault.WithAttestationChallenge(),
+	}
+
+	enclaveClient, err := client.EnclaveClient(enclave.Hostname, []attestation.PCRs{enclavePCRs}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = getEnclave(t, enclaveClient, enclave.URL+"/hello")
+	assert.Error(t, err)
+}
+
+func TestEnclaveValidatesPolledPCRs(t *testing.T) {
+	t.Parallel()
+
+	enclave, client := startEnclave(t, evervault.WithAttestationPollingInterval(10*time.Millisecond))
+
+	var polled atomic.Pointer[attestation.PCRs]
+
+	polled.Store(&enclavePCRs)
+
+	provider := func() ([]attestation.PCRs, error) {
+		return []attestation.PCRs{*polled.Load()}, nil
+	}
+
+	session, err := client.OpenEnclave(enclave.Hostname, provider, enclave.Options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	body, err := getEnclave(t, session.HTTPClient(), enclave.URL+"/hello")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello from the enclave", body)
+
+	polled.Store(&attestation.PCRs{PCR0: "not hex", AllowWildcard: true})
+	assert.Eventually(t, func() bool {
+		_, err := getEnclave(t, session.HTTPClient(), enclave.URL+"/hello")
+		return errors.Is(err, evervault.ErrInvalidPCRs)
+	}, time.Second, 10*time.Millisecond)
+
+	polled.Store(&attestation.PCRs{AllowWildcard: true})
+	assert.Eventually(t, func() bool {
+		_, err := getEnclave(t, session.HTTPClient(), enclave.URL+"/hello")
+		return errors.Is(err, evervault.ErrNoPCRs)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestEnclaveCacheLogsToEachClient(t *testing.T) {
+	t.Parallel()
+
+	enclave := evervaulttest.NewEnclave(enclavePCRs, nil)
+	defer enclave.Close()
+
+	server := evervaulttest.NewServer()
+	defer server.Close()
+
+	loggers := []*recordingLogger{{}, {}}
+
+	for _, logger := range loggers {
+		client, err := server.Client(evervault.WithLogger(logger), singleAttestationAttempt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer client.Close()
+
+		// The TLS certificate of the enclave is not trusted, so fetching the attestation doc fails.
+		session, err := client.OpenEnclave(enclave.Hostname, attestation.BuildStaticPcrProvider(
+			[]attestation.PCRs{enclavePCRs}), evervault.WithAttestationRoots(enclave.AttestationRoots()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer session.Close()
+	}
+
+	for _, logger := range loggers {
+		assert.True(t, logger.logged("could not load attestation doc"))
+	}
+}
+
+func TestEnclaveCacheTracesToEachClient(t *testing.T) {
+	t.Parallel()
+
+	enclave := evervaulttest.NewEnclave(enclavePCRs, nil)
+	defer enclave.Close()
+
+	server := evervaulttest.NewServer()
+	defer server.Close()
+
+	instrumentations := []*recordingInstrumentation{{}, {}}
+	opts := enclave.Options()
+
+	for _, instrumentation := range instrumentations {
+		client, err := server.Client(evervault.WithLogger(evervault.DiscardLogger),
+			evervault.WithInstrumentation(instrumentation))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer client.Close()
+
+		session, err := client.OpenEnclave(enclave.Hostname, attestation.BuildStaticPcrProvider(
+			[]attestation.PCRs{enclavePCRs}), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer session.Close()
+	}
+
+	for _, instrumentation := range instrumentations {
+		assert.True(t, instrumentation.started(evervault.OperationAttestationDocFetch))
+	}
+}
+
+// silentListener accepts connections without ever answering them, so TLS handshakes and attestation doc fetches
+// with it only end when they time out.
+func silentListener(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mutex sync.Mutex
+		conns []net.Conn
+	)
+
+	t.Cleanup(func() {
+		listener.Close()
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		for _, conn := range conns {
+			conn.Close()
+		}
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			mutex.Lock()
+			conns = append(conns, conn)
+			mutex.Unlock()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestEnclaveDialHonoursContextDeadline(t *testing.T) {
+	t.Parallel()
+
+	enclave, client := startEnclave(t)
+
+	session, err := client.OpenEnclave(enclave.Hostname, attestation.BuildStaticPcrProvider(
+		[]attestation.PCRs{enclavePCRs}), append(enclave.Options(), evervault.WithDialTimeout(time.Minute))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	_, err = session.DialContext(ctx, "tcp", silentListener(t))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestEnclaveDialHonoursDialTimeout(t *testing.T) {
+	t.Parallel()
+
+	enclave, client := startEnclave(t)
+
+	session, err := client.OpenEnclave(enclave.Hostname, attestation.BuildStaticPcrProvider(
+		[]attestation.PCRs{enclavePCRs}), append(enclave.Options(), evervault.WithDialTimeout(100*time.Millisecond))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+
+	_, err = session.DialContext(context.Background(), "tcp", silentListener(t))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestEnclaveDialHonoursAttestationDocTimeout(t *testing.T) {
+	t.Parallel()
+
+	// The initial attestation doc fetch from the silent listener takes the Config timeout, dials take the option.
+	enclave, client := startEnclave(t, func(config *evervault.Config) error {
+		config.AttestationDocTimeout = 2 * time.Second
+
+		return nil
+	})
+
+	opts := append(enclave.Options(), evervault.WithAttestationChallenge(),
+		evervault.WithAttestationDocTimeout(100*time.Millisecond))
+
+	session, err := client.OpenEnclave(silentListener(t), attestation.BuildStaticPcrProvider(
+		[]attestation.PCRs{enclavePCRs}), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+
+	_, err = session.DialContext(context.Background(), "tcp", enclave.Hostname)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
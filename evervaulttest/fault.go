@@ -0,0 +1,103 @@
+package evervaulttest
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Fault is injected into the responses of the Server with InjectFault, to test how code handles a slow or
+// failing Evervault API.
+type Fault struct {
+	Path          string        // Path prefix of the requests the fault applies to, every request if empty.
+	Latency       time.Duration // Delay before the request is handled.
+	StatusCode    int           // Status responded with instead of handling the request, handled if zero.
+	MalformedJSON bool          // Respond with a body that is not valid JSON instead of handling the request.
+	Count         int           // Number of requests the fault applies to, every request if zero.
+}
+
+// InjectFault adds a fault to the responses of the Server. When several faults apply to a request the first one
+// injected is used.
+//
+//	// The next two requests to run a Function fail with 503 Service Unavailable.
+//	server.InjectFault(evervaulttest.Fault{
+//		Path:       "/functions/",
+//		StatusCode: http.StatusServiceUnavailable,
+//		Count:      2,
+//	})
+func (s *Server) InjectFault(fault Fault) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.faults = append(s.faults, &fault)
+}
+
+// ClearFaults removes every fault injected into the Server.
+func (s *Server) ClearFaults() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.faults = nil
+}
+
+// nextFault returns the fault for a request, counting it against the fault. It returns false if none apply.
+func (s *Server) nextFault(path string) (Fault, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, fault := range s.faults {
+		if !strings.HasPrefix(path, fault.Path) {
+			continue
+		}
+
+		if fault.Count > 0 {
+			fault.Count--
+
+			if fault.Count == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+
+		return *fault, true
+	}
+
+	return Fault{}, false
+}
+
+// injectFaults wraps the handler of the Server to apply injected faults.
+func (s *Server) injectFaults(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		fault, ok := s.nextFault(request.URL.Path)
+		if !ok {
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		if fault.Latency > 0 {
+			timer := time.NewTimer(fault.Latency)
+			defer timer.Stop()
+
+			select {
+			case <-timer.C:
+			case <-request.Context().Done():
+				return
+			}
+		}
+
+		switch {
+		case fault.MalformedJSON:
+			status := fault.StatusCode
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(status)
+			_, _ = writer.Write([]byte(`{"malformed":`))
+		case fault.StatusCode != 0:
+			writeError(writer, fault.StatusCode, "injected-fault", "Fault injected by evervaulttest")
+		default:
+			next.ServeHTTP(writer, request)
+		}
+	})
+}
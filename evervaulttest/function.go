@@ -0,0 +1,323 @@
+package evervaulttest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ErrFunctionTimeout can be returned by a FunctionHandler to respond to the run as a Function that timed out, which
+// the Client returns as an evervault.FunctionTimeoutError.
+var ErrFunctionTimeout = errors.New("Function execution exceeded the allotted time and has timed out")
+
+// ErrFunctionNotReady can be returned by a FunctionHandler to respond to the run as a Function that is not ready to
+// be invoked, which the Client returns as an evervault.FunctionNotReadyError.
+var ErrFunctionNotReady = errors.New("The Function is not ready to be invoked yet")
+
+// defaultRunTokenExpiry is how long run tokens are valid for when created without an expiry.
+const defaultRunTokenExpiry = 5 * time.Minute
+
+// FunctionHandler handles a run of a Function registered with Server.HandleFunction. It is called with the JSON
+// payload of the run and returns the result of the Function, which must be JSON serialisable. Asynchronous runs are
+// called with a context that is cancelled when the Server is closed.
+//
+// Returning ErrFunctionTimeout or ErrFunctionNotReady responds as the Evervault API does for those errors. Any other
+// error fails the run with the error as its message, a FunctionError also sets the stack of the failure.
+type FunctionHandler func(ctx context.Context, payload json.RawMessage) (any, error)
+
+// FunctionError fails a Function run with a message and stack, returned by the Client as an
+// evervault.FunctionRuntimeError.
+type FunctionError struct {
+	Message string
+	Stack   string
+}
+
+func (e FunctionError) Error() string {
+	return e.Message
+}
+
+// MarshalJSON encodes the error with the lower case field names of the Evervault API.
+func (e FunctionError) MarshalJSON() ([]byte, error) {
+	//nolint:wrapcheck
+	return json.Marshal(runError(e))
+}
+
+// runError is the error of a failed Function run.
+type runError struct {
+	Message string `json:"message"`
+	Stack   string `json:"stack"`
+}
+
+// TypedHandler returns a FunctionHandler that decodes the payload of the run into In, for Functions invoked with
+// evervault.RunFunctionTyped. Runs with payloads that cannot be decoded fail.
+func TypedHandler[In, Out any](handler func(ctx context.Context, in In) (Out, error)) FunctionHandler {
+	return func(ctx context.Context, payload json.RawMessage) (any, error) {
+		var in In
+		if err := json.Unmarshal(payload, &in); err != nil {
+			return nil, FunctionError{Message: "invalid payload: " + err.Error()}
+		}
+
+		return handler(ctx, in)
+	}
+}
+
+// HandleFunction registers the handler for runs of the Function with the given name, replacing any previous
+// handler. Runs of Functions without a handler respond with 404 Not Found.
+func (s *Server) HandleFunction(name string, handler FunctionHandler) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.functions[name] = handler
+}
+
+// functionRun is the state of a Function run, in the format of the Evervault API.
+type functionRun struct {
+	ID       string         `json:"id"`
+	Status   string         `json:"status"`
+	Result   any            `json:"result,omitempty"`
+	Error    *FunctionError `json:"error,omitempty"`
+	function string
+}
+
+// runToken is a Function run token issued by /v2/functions/{name}/run-token.
+type runToken struct {
+	function  string
+	payload   json.RawMessage
+	expiry    time.Time
+	singleUse bool
+}
+
+// runTokenRequest is the body of a request to create a Function run token.
+type runTokenRequest struct {
+	Payload   json.RawMessage `json:"payload"`
+	Expiry    int64           `json:"expiry"`
+	SingleUse bool            `json:"singleUse"`
+}
+
+// runRequest is the body of a request to run a Function.
+type runRequest struct {
+	Payload json.RawMessage `json:"payload"`
+	Async   bool            `json:"async"`
+}
+
+// handleFunctions serves Function runs at /functions/{name}/runs and asynchronous runs at
+// /functions/{name}/runs/{id}. Runs are authorized with the Server credentials or a run token for the run as a
+// Bearer token.
+func (s *Server) handleFunctions(writer http.ResponseWriter, request *http.Request) {
+	token, bearer := bearerToken(request)
+	if !bearer && !authorized(request) {
+		writeError(writer, http.StatusUnauthorized, "unauthorized", "Invalid API key")
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(request.URL.Path, "/functions/"), "/")
+
+	switch {
+	case len(parts) == 3 && parts[1] == "runs" && request.Method == http.MethodGet && !bearer:
+		s.getRun(writer, parts[0], parts[2])
+	case len(parts) == 2 && parts[1] == "runs" && request.Method == http.MethodPost:
+		s.startRun(writer, request, parts[0], token)
+	default:
+		writeError(writer, http.StatusNotFound, "resource-not-found", "Not Found")
+	}
+}
+
+// bearerToken returns the Bearer token the request is authorized with, if any.
+func bearerToken(request *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return "", false
+	}
+
+	return token, true
+}
+
+// startRun runs the Function, redeeming the run token unless it is empty.
+func (s *Server) startRun(writer http.ResponseWriter, request *http.Request, name, token string) {
+	s.mutex.Lock()
+	handler, ok := s.functions[name]
+	s.mutex.Unlock()
+
+	if !ok {
+		writeError(writer, http.StatusNotFound, "resource-not-found", "Function "+name+" not found")
+		return
+	}
+
+	var body runRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		writeError(writer, http.StatusBadRequest, "invalid-request", "Request body must be a JSON object")
+		return
+	}
+
+	if token != "" && !s.redeemRunToken(token, name, body.Payload) {
+		writeError(writer, http.StatusForbidden, "forbidden", "Run token is expired or not valid for this payload")
+		return
+	}
+
+	run := &functionRun{ID: newID("func_run_"), Status: "pending", function: name}
+
+	if !body.Async {
+		result, err := handler(request.Context(), body.Payload)
+		if errors.Is(err, ErrFunctionTimeout) {
+			writeError(writer, http.StatusRequestTimeout, "functions/request-timeout", err.Error())
+			return
+		}
+
+		if errors.Is(err, ErrFunctionNotReady) {
+			writeError(writer, http.StatusConflict, "functions/function-not-ready", err.Error())
+			return
+		}
+
+		run.finish(result, err)
+		writeJSON(writer, http.StatusOK, run)
+
+		return
+	}
+
+	s.mutex.Lock()
+	s.runs[run.ID] = run
+	s.mutex.Unlock()
+
+	writeJSON(writer, http.StatusOK, *run)
+
+	s.running.Add(1)
+
+	go func() {
+		defer s.running.Done()
+
+		s.mutex.Lock()
+		run.Status = "running"
+		s.mutex.Unlock()
+
+		result, err := handler(s.ctx, body.Payload)
+
+		s.mutex.Lock()
+		run.finish(result, err)
+		s.mutex.Unlock()
+	}()
+}
+
+func (s *Server) getRun(writer http.ResponseWriter, name, id string) {
+	s.mutex.Lock()
+	run, ok := s.runs[id]
+	ok = ok && run.function == name
+
+	var current functionRun
+	if ok {
+		current = *run
+	}
+	s.mutex.Unlock()
+
+	if !ok {
+		writeError(writer, http.StatusNotFound, "resource-not-found", "Function run "+id+" not found")
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, current)
+}
+
+// finish sets the outcome of the run from the result and error of its handler.
+func (r *functionRun) finish(result any, err error) {
+	if err == nil {
+		r.Status = "success"
+		r.Result = result
+
+		return
+	}
+
+	functionErr := FunctionError{Message: err.Error()}
+	errors.As(err, &functionErr)
+
+	r.Status = "failure"
+	r.Error = &functionErr
+}
+
+// handleRunToken serves Function run tokens at /v2/functions/{name}/run-token.
+func (s *Server) handleRunToken(writer http.ResponseWriter, request *http.Request) {
+	if !authorized(request) {
+		writeError(writer, http.StatusUnauthorized, "unauthorized", "Invalid API key")
+		return
+	}
+
+	name, ok := strings.CutSuffix(strings.TrimPrefix(request.URL.Path, "/v2/functions/"), "/run-token")
+	if !ok || strings.Contains(name, "/") || request.Method != http.MethodPost {
+		writeError(writer, http.StatusNotFound, "resource-not-found", "Not Found")
+		return
+	}
+
+	s.mutex.Lock()
+	_, ok = s.functions[name]
+	s.mutex.Unlock()
+
+	if !ok {
+		writeError(writer, http.StatusNotFound, "resource-not-found", "Function "+name+" not found")
+		return
+	}
+
+	var body runTokenRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil || len(body.Payload) == 0 ||
+		string(body.Payload) == "null" {
+		writeError(writer, http.StatusBadRequest, "invalid-request", "Request body must have a payload")
+		return
+	}
+
+	now := time.Now()
+	expiry := now.Add(defaultRunTokenExpiry)
+
+	if body.Expiry != 0 {
+		expiry = time.UnixMilli(body.Expiry)
+	}
+
+	if !expiry.After(now) {
+		writeError(writer, http.StatusUnprocessableEntity, "invalid-request", "Expiry must be in the future")
+		return
+	}
+
+	token := newID("run_token_")
+
+	s.mutex.Lock()
+	s.runTokens[token] = runToken{function: name, payload: body.Payload, expiry: expiry, singleUse: body.SingleUse}
+	s.mutex.Unlock()
+
+	writeJSON(writer, http.StatusOK, map[string]any{"token": token, "expiry": expiry.UnixMilli()})
+}
+
+// redeemRunToken reports whether a run token is unexpired and was issued for a run of the Function with the
+// payload. Single use tokens are removed once redeemed.
+func (s *Server) redeemRunToken(token, name string, payload json.RawMessage) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	issued, ok := s.runTokens[token]
+	if !ok || issued.function != name || !time.Now().Before(issued.expiry) {
+		return false
+	}
+
+	var issuedPayload, runPayload any
+	if json.Unmarshal(issued.payload, &issuedPayload) != nil || json.Unmarshal(payload, &runPayload) != nil ||
+		!reflect.DeepEqual(issuedPayload, runPayload) {
+		return false
+	}
+
+	if issued.singleUse {
+		delete(s.runTokens, token)
+	}
+
+	return true
+}
+
+// newID returns a random identifier with the prefix.
+func newID(prefix string) string {
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+
+	return prefix + hex.EncodeToString(id)
+}
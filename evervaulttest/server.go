@@ -0,0 +1,253 @@
+// Package evervaulttest provides an in-process emulator of the Evervault API for tests and local development.
+//
+// The Server emulates the App public key, decryption of values encrypted with it, client-side tokens, Function
+// runs and run tokens, the Relay CA and enclave attestation docs, so code using an evervault.Client can be tested
+// without a live Evervault App. Faults such as latency, server errors and malformed responses can be injected with
+// Server.InjectFault. Enclave emulates an enclave serving synthetic Nitro Enclaves attestation docs.
+//
+//	server := evervaulttest.NewServer()
+//	defer server.Close()
+//
+//	server.HandleFunction("hello-function", func(ctx context.Context, payload json.RawMessage) (any, error) {
+//		return map[string]any{"message": "Hello!"}, nil
+//	})
+//
+//	client, err := server.Client()
+package evervaulttest
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/evervault/evervault-go"
+	"github.com/evervault/evervault-go/internal/crypto"
+)
+
+// Credentials accepted by the Server and used by Server.Client.
+const (
+	AppUUID = "app_evervaulttest"
+	APIKey  = "ev:key:1:evervaulttest"
+)
+
+// Server is an in-process Evervault API. Function handlers, decrypted values, attestation docs and faults can be
+// registered while the Server is running.
+type Server struct {
+	// URL of the Server, used as the Evervault API URL.
+	URL string
+
+	server         *httptest.Server
+	key            *ecdh.PrivateKey
+	caCert         []byte
+	mutex          sync.Mutex
+	functions      map[string]FunctionHandler
+	runs           map[string]*functionRun
+	decrypted      map[string]any
+	tokens         map[string]clientSideToken
+	runTokens      map[string]runToken
+	attestationDoc []byte
+	faults         []*Fault
+	running        sync.WaitGroup
+	ctx            context.Context //nolint:containedctx
+	cancel         context.CancelFunc
+}
+
+// NewServer starts a Server with a new App key pair and Relay CA. It must be closed with Close when no longer
+// needed.
+func NewServer() *Server {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		panic(fmt.Sprintf("evervaulttest: error generating app key %s", err))
+	}
+
+	caCert, err := generateCA()
+	if err != nil {
+		panic(fmt.Sprintf("evervaulttest: error generating relay CA %s", err))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	server := &Server{
+		ctx:       ctx,
+		cancel:    cancel,
+		key:       key,
+		caCert:    caCert,
+		functions: make(map[string]FunctionHandler),
+		runs:      make(map[string]*functionRun),
+		decrypted: make(map[string]any),
+		tokens:    make(map[string]clientSideToken),
+		runTokens: make(map[string]runToken),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cages/key", server.handleKey)
+	mux.HandleFunc("/decrypt", server.handleDecrypt)
+	mux.HandleFunc("/client-side-tokens", server.handleClientSideToken)
+	mux.HandleFunc("/functions/", server.handleFunctions)
+	mux.HandleFunc("/v2/functions/", server.handleRunToken)
+	mux.HandleFunc("/ca.crt", server.handleCA)
+	mux.HandleFunc("/.well-known/attestation", server.handleAttestation)
+
+	server.server = httptest.NewServer(server.injectFaults(mux))
+	server.URL = server.server.URL
+
+	return server
+}
+
+// Close shuts down the Server, cancelling the context of asynchronous Function runs and waiting for their handlers
+// to return.
+func (s *Server) Close() {
+	s.cancel()
+	s.server.Close()
+	s.running.Wait()
+}
+
+// Client returns an evervault.Client using the Server as the Evervault API and Relay CA URL. Options are applied
+// after the URLs so they can configure anything else about the Client.
+func (s *Server) Client(opts ...evervault.Option) (*evervault.Client, error) {
+	opts = append([]evervault.Option{evervault.WithAPIURL(s.URL), evervault.WithCAURL(s.CAURL())}, opts...)
+
+	return evervault.New(AppUUID, APIKey, opts...)
+}
+
+// PublicKey returns the uncompressed public key of the App, the key values are encrypted with.
+func (s *Server) PublicKey() []byte {
+	return s.key.PublicKey().Bytes()
+}
+
+// CAURL returns the URL the Relay CA is served at.
+func (s *Server) CAURL() string {
+	return s.URL + "/ca.crt"
+}
+
+// CACertificate returns the PEM encoded Relay CA served by the Server.
+func (s *Server) CACertificate() []byte {
+	return s.caCert
+}
+
+// SetAttestationDoc sets the attestation doc served at /.well-known/attestation. Requests for the doc respond with
+// 404 Not Found until it is set.
+func (s *Server) SetAttestationDoc(doc []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.attestationDoc = doc
+}
+
+func (s *Server) handleKey(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		writeError(writer, http.StatusMethodNotAllowed, "method-not-allowed", "Method Not Allowed")
+		return
+	}
+
+	if !authorized(request) {
+		writeError(writer, http.StatusUnauthorized, "unauthorized", "Invalid API key")
+		return
+	}
+
+	publicKey := s.PublicKey()
+
+	writeJSON(writer, http.StatusOK, evervault.KeysResponse{
+		TeamUUID:                "team_evervaulttest",
+		EcdhP256Key:             base64.StdEncoding.EncodeToString(crypto.CompressPublicKey(publicKey)),
+		EcdhP256KeyUncompressed: base64.StdEncoding.EncodeToString(publicKey),
+	})
+}
+
+func (s *Server) handleCA(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		writeError(writer, http.StatusMethodNotAllowed, "method-not-allowed", "Method Not Allowed")
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/x-pem-file")
+	_, _ = writer.Write(s.caCert)
+}
+
+func (s *Server) handleAttestation(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		writeError(writer, http.StatusMethodNotAllowed, "method-not-allowed", "Method Not Allowed")
+		return
+	}
+
+	s.mutex.Lock()
+	doc := s.attestationDoc
+	s.mutex.Unlock()
+
+	if doc == nil {
+		writeError(writer, http.StatusNotFound, "resource-not-found", "No attestation doc has been set")
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, map[string]string{"attestation_doc": base64.StdEncoding.EncodeToString(doc)})
+}
+
+// generateCA returns a new self-signed PEM encoded CA certificate.
+func generateCA() ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("error generating key %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "evervaulttest Relay CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating certificate %w", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+// authorized reports whether a request has the Server credentials, as Basic auth or an API-KEY header.
+func authorized(request *http.Request) bool {
+	if appUUID, apiKey, ok := request.BasicAuth(); ok {
+		return equal(appUUID, AppUUID) && equal(apiKey, APIKey)
+	}
+
+	return equal(request.Header.Get("API-KEY"), APIKey)
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func writeJSON(writer http.ResponseWriter, status int, value any) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+
+	//nolint:errchkjson
+	_ = json.NewEncoder(writer).Encode(value)
+}
+
+// writeError writes an error in the format of the Evervault API.
+func writeError(writer http.ResponseWriter, status int, code, detail string) {
+	writeJSON(writer, status, map[string]any{
+		"status": status,
+		"code":   code,
+		"title":  http.StatusText(status),
+		"detail": detail,
+	})
+}
@@ -0,0 +1,485 @@
+//go:build unit_test
+// +build unit_test
+
+package evervaulttest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/evervault/evervault-go"
+	"github.com/evervault/evervault-go/evervaulttest"
+	"github.com/stretchr/testify/assert"
+)
+
+type greetingInput struct {
+	Name string `json:"name"`
+}
+
+type greetingOutput struct {
+	Message string `json:"message"`
+}
+
+func greet(_ context.Context, in greetingInput) (greetingOutput, error) {
+	return greetingOutput{Message: "Hello " + in.Name}, nil
+}
+
+func newClient(t *testing.T, server *evervaulttest.Server) *evervault.Client {
+	t.Helper()
+
+	client, err := server.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+func TestServerCredentials(t *testing.T) {
+	t.Parallel()
+
+	server := evervaulttest.NewServer()
+	defer server.Close()
+
+	_, err := server.Client()
+	assert.NoError(t, err)
+
+	_, err = evervault.New("app_uuid", "api_key", evervault.WithAPIURL(server.URL))
+	assert.ErrorIs(t, err, evervault.ErrUnauthorized)
+}
+
+func TestServerRunFunction(t *testing.T) {
+	t.Parallel()
+
+	server := evervaulttest.NewServer()
+	defer server.Close()
+
+	server.HandleFunction("hello", evervaulttest.TypedHandler(greet))
+
+	client := newClient(t, server)
+
+	res, err := client.RunFunction("hello", map[string]any{"name": "john"})
+	assert.NoError(t, err)
+	assert.Equal(t, "success", res.Status)
+	assert.Equal(t, "Hello john", res.Result["message"])
+
+	out, meta, err := evervault.RunFunctionTyped[greetingInput, greetingOutput](
+		context.Background(), client, "hello", greetingInput{Name: "jane"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello jane", out.Message)
+	assert.NotEmpty(t, meta.ID)
+
+	_, err = client.RunFunction("missing", map[string]any{})
+	assert.ErrorIs(t, err, evervault.ErrNotFound)
+}
+
+func TestServerRunFunctionErrors(t *testing.T) {
+	t.Parallel()
+
+	server := evervaulttest.NewServer()
+	defer server.Close()
+
+	server.HandleFunction("timeout", func(context.Context, json.RawMessage) (any, error) {
+		return nil, evervaulttest.ErrFunctionTimeout
+	})
+	server.HandleFunction("not-ready", func(context.Context, json.RawMessage) (any, error) {
+		return nil, evervaulttest.ErrFunctionNotReady
+	})
+	server.HandleFunction("failure", func(context.Context, json.RawMessage) (any, error) {
+		return nil, evervaulttest.FunctionError{Message: "Uh oh!", Stack: "Error: Uh oh!..."}
+	})
+
+	client := newClient(t, server)
+
+	_, err := client.RunFunction("timeout", map[string]any{})
+	assert.ErrorAs(t, err, &evervault.FunctionTimeoutError{})
+
+	_, err = client.RunFunction("not-ready", map[string]any{})
+	assert.ErrorAs(t, err, &evervault.FunctionNotReadyError{})
+
+	_, err = client.RunFunction("failure", map[string]any{})
+
+	var runtimeErr evervault.FunctionRuntimeError
+	if assert.ErrorAs(t, err, &runtimeErr) {
+		assert.Equal(t, "Uh oh!", runtimeErr.ErrorBody.Message)
+		assert.Equal(t, "Error: Uh oh!...", runtimeErr.ErrorBody.Stack)
+		assert.NotEmpty(t, runtimeErr.ID)
+	}
+}
+
+func TestServerAsyncFunctionRun(t *testing.T) {
+	t.Parallel()
+
+	server := evervaulttest.NewServer()
+	defer server.Close()
+
+	release := make(chan struct{})
+
+	server.HandleFunction("hello", func(ctx context.Context, payload json.RawMessage) (any, error) {
+		<-release
+
+		return evervaulttest.TypedHandler(greet)(ctx, payload)
+	})
+	server.HandleFunction("failure", func(context.Context, json.RawMessage) (any, error) {
+		return nil, errors.New("Uh oh!")
+	})
+
+	client := newClient(t, server)
+	client.Config.FunctionRunPollingInterval = time.Millisecond
+
+	run, err := client.StartFunctionRun("hello", map[string]any{"name": "john"})
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", run.Status)
+
+	pending, err := client.GetFunctionRun("hello", run.ID)
+	assert.NoError(t, err)
+	assert.NotEqual(t, "success", pending.Status)
+
+	close(release)
+
+	res, err := client.WaitFunctionRun(context.Background(), "hello", run.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello john", res.Result["message"])
+
+	run, err = client.StartFunctionRun("failure", map[string]any{})
+	assert.NoError(t, err)
+
+	_, err = client.WaitFunctionRun(context.Background(), "failure", run.ID)
+	assert.ErrorAs(t, err, &evervault.FunctionRuntimeError{})
+
+	_, err = client.GetFunctionRun("failure", "func_run_missing")
+	assert.ErrorIs(t, err, evervault.ErrNotFound)
+
+	_, err = client.GetFunctionRun("hello", run.ID)
+	assert.ErrorIs(t, err, evervault.ErrNotFound)
+}
+
+func TestServerCloseCancelsFunctionRuns(t *testing.T) {
+	t.Parallel()
+
+	server := evervaulttest.NewServer()
+
+	started := make(chan struct{})
+
+	server.HandleFunction("blocking", func(ctx context.Context, _ json.RawMessage) (any, error) {
+		close(started)
+		<-ctx.Done()
+
+		return nil, ctx.Err()
+	})
+
+	_, err := newClient(t, server).StartFunctionRun("blocking", map[string]any{})
+	assert.NoError(t, err)
+
+	<-started
+
+	closed := make(chan struct{})
+
+	go func() {
+		server.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not cancel the Function run")
+	}
+}
+
+func TestServerRunToken(t *testing.T) {
+	t.Parallel()
+
+	server := evervaulttest.NewServer()
+	defer server.Close()
+
+	server.HandleFunction("hello", evervaulttest.TypedHandler(greet))
+
+	client := newClient(t, server)
+
+	res, err := client.CreateFunctionRunToken("hello", map[string]any{"name": "john"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, res.Token)
+	assert.Greater(t, res.Expiry, time.Now().UnixMilli())
+
+	expiry := time.Now().Add(time.Minute)
+
+	res, err = client.CreateFunctionRunTokenWithOptions("hello", map[string]any{"name": "john"},
+		evervault.RunTokenOptions{Expiry: expiry})
+	assert.NoError(t, err)
+	assert.Equal(t, expiry.UnixMilli(), res.Expiry)
+
+	_, err = client.CreateFunctionRunToken("missing", map[string]any{})
+	assert.ErrorIs(t, err, evervault.ErrNotFound)
+}
+
+func TestServerRunTokenSingleUse(t *testing.T) {
+	t.Parallel()
+
+	server := evervaulttest.NewServer()
+	defer server.Close()
+
+	server.HandleFunction("hello", evervaulttest.TypedHandler(greet))
+
+	client := newClient(t, server)
+
+	res, err := client.CreateFunctionRunTokenWithOptions("hello", map[string]any{"name": "john"},
+		evervault.RunTokenOptions{SingleUse: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, http.StatusOK, runWithToken(t, server, "hello", res.Token, `{"name": "john"}`).StatusCode)
+	assert.Equal(t, http.StatusForbidden, runWithToken(t, server, "hello", res.Token, `{"name": "john"}`).StatusCode)
+}
+
+func TestServerRunTokenOptionsRunSamePayload(t *testing.T) {
+	t.Parallel()
+
+	server := evervaulttest.NewServer()
+	defer server.Close()
+
+	server.HandleFunction("hello", evervaulttest.TypedHandler(greet))
+	server.HandleFunction("other", evervaulttest.TypedHandler(greet))
+
+	client := newClient(t, server)
+
+	plain, err := client.CreateFunctionRunToken("hello", map[string]any{"name": "john"})
+	assert.NoError(t, err)
+
+	withOptions, err := client.CreateFunctionRunTokenWithOptions("hello", map[string]any{"name": "john"},
+		evervault.RunTokenOptions{Expiry: time.Now().Add(time.Minute)})
+	assert.NoError(t, err)
+
+	for _, token := range []string{plain.Token, withOptions.Token} {
+		assert.Equal(t, http.StatusOK, runWithToken(t, server, "hello", token, `{"name": "john"}`).StatusCode)
+		assert.Equal(t, http.StatusForbidden, runWithToken(t, server, "hello", token, `{"name": "jane"}`).StatusCode)
+		assert.Equal(t, http.StatusForbidden, runWithToken(t, server, "other", token, `{"name": "john"}`).StatusCode)
+	}
+}
+
+func runWithToken(t *testing.T, server *evervaulttest.Server, name, token, payload string) *http.Response {
+	t.Helper()
+
+	body := []byte(`{"payload": ` + payload + `}`)
+
+	request, err := http.NewRequest(http.MethodPost, server.URL+"/functions/"+name+"/runs", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response.Body.Close()
+
+	return response
+}
+
+func TestServerDecrypt(t *testing.T) {
+	t.Parallel()
+
+	server := evervaulttest.NewServer()
+	defer server.Close()
+
+	client := newClient(t, server)
+
+	encryptedString, err := client.EncryptString("hello")
+	assert.NoError(t, err)
+
+	decrypted, err := client.DecryptString(encryptedString)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", decrypted)
+
+	encryptedInt, err := client.EncryptIntWithDataRole(42, "role")
+	assert.NoError(t, err)
+
+	number, err := client.DecryptInt(encryptedInt)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, number)
+
+	encryptedFloat, err := client.EncryptFloat64(1.5)
+	assert.NoError(t, err)
+
+	float, err := client.DecryptFloat64(encryptedFloat)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, float)
+
+	encryptedBool, err := client.EncryptBool(true)
+	assert.NoError(t, err)
+
+	boolean, err := client.DecryptBool(encryptedBool)
+	assert.NoError(t, err)
+	assert.True(t, boolean)
+
+	server.AddDecrypted("ev:fixture", "registered")
+
+	decrypted, err = client.DecryptString("ev:fixture")
+	assert.NoError(t, err)
+	assert.Equal(t, "registered", decrypted)
+
+	other := evervaulttest.NewServer()
+	defer other.Close()
+
+	otherClient := newClient(t, other)
+
+	encryptedElsewhere, err := otherClient.EncryptString("hello")
+	assert.NoError(t, err)
+
+	_, err = client.DecryptString(encryptedElsewhere)
+	assert.ErrorAs(t, err, &evervault.ValidationError{})
+}
+
+func TestServerClientSideToken(t *testing.T) {
+	t.Parallel()
+
+	server := evervaulttest.NewServer()
+	defer server.Close()
+
+	client := newClient(t, server)
+
+	encrypted, err := client.EncryptString("hello")
+	assert.NoError(t, err)
+
+	token, err := client.CreateClientSideDecryptToken(encrypted)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token.Token)
+	assert.InDelta(t, time.Now().Add(5*time.Minute).UnixMilli(), token.Expiry, float64(time.Minute.Milliseconds()))
+
+	decrypted := decryptWithToken(t, server, token.Token, encrypted)
+	assert.Equal(t, http.StatusOK, decrypted.StatusCode)
+
+	other, err := client.EncryptString("other")
+	assert.NoError(t, err)
+
+	decrypted = decryptWithToken(t, server, token.Token, other)
+	assert.Equal(t, http.StatusForbidden, decrypted.StatusCode)
+
+	_, err = client.CreateClientSideDecryptToken(encrypted, time.Now().Add(time.Hour))
+	assert.Error(t, err)
+}
+
+func decryptWithToken(t *testing.T, server *evervaulttest.Server, token, encrypted string) *http.Response {
+	t.Helper()
+
+	body, err := json.Marshal(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := http.NewRequest(http.MethodPost, server.URL+"/decrypt", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response.Body.Close()
+
+	return response
+}
+
+func TestServerRelayCA(t *testing.T) {
+	t.Parallel()
+
+	server := evervaulttest.NewServer()
+	defer server.Close()
+
+	client := newClient(t, server)
+
+	_, err := client.OutboundRelayClient()
+	assert.NoError(t, err)
+
+	response, err := http.Get(server.CAURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	ca, err := io.ReadAll(response.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, server.CACertificate(), ca)
+}
+
+func TestServerAttestationDoc(t *testing.T) {
+	t.Parallel()
+
+	server := evervaulttest.NewServer()
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/.well-known/attestation")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response.Body.Close()
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+
+	server.SetAttestationDoc([]byte("attestation doc"))
+
+	response, err = http.Get(server.URL + "/.well-known/attestation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	var body struct {
+		AttestationDoc []byte `json:"attestation_doc"`
+	}
+
+	assert.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+	assert.Equal(t, []byte("attestation doc"), body.AttestationDoc)
+}
+
+func TestServerFaults(t *testing.T) {
+	t.Parallel()
+
+	server := evervaulttest.NewServer()
+	defer server.Close()
+
+	server.HandleFunction("hello", evervaulttest.TypedHandler(greet))
+
+	server.InjectFault(evervaulttest.Fault{Path: "/cages/key", StatusCode: http.StatusServiceUnavailable, Count: 2})
+
+	client, err := server.Client(
+		evervault.WithLogger(evervault.DiscardLogger),
+		evervault.WithRetryPolicy(evervault.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}),
+	)
+	assert.NoError(t, err)
+
+	server.InjectFault(evervaulttest.Fault{Path: "/functions/", MalformedJSON: true, Count: 1})
+
+	_, err = client.RunFunction("hello", map[string]any{"name": "john"})
+	assert.Error(t, err)
+
+	_, err = client.RunFunction("hello", map[string]any{"name": "john"})
+	assert.NoError(t, err)
+
+	server.InjectFault(evervaulttest.Fault{Latency: 50 * time.Millisecond})
+
+	start := time.Now()
+	_, err = client.RunFunction("hello", map[string]any{"name": "john"})
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	server.ClearFaults()
+	server.InjectFault(evervaulttest.Fault{StatusCode: http.StatusInternalServerError})
+
+	_, err = client.RunFunction("hello", map[string]any{"name": "john"})
+	assert.ErrorAs(t, err, &evervault.ServerError{})
+}
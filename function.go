@@ -1,22 +1,42 @@
 package evervault
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"time"
 )
 
 // Struct containing a token for Function invocation.
 // RunTokenResponse.Token can be used to invoke a function run by the user.
+// RunTokenResponse.Expiry is the time the token expires at in milliseconds since the Unix epoch.
 type RunTokenResponse struct {
-	Token string `json:"token"`
+	Token  string `json:"token"`
+	Expiry int64  `json:"expiry"`
+}
+
+// RunTokenOptions configures a token created with CreateFunctionRunTokenWithOptions.
+type RunTokenOptions struct {
+	// Expiry is the time the token expires, the Evervault default is used if it is zero.
+	Expiry time.Time
+	// SingleUse limits the token to a single Function run.
+	SingleUse bool
+}
+
+// runTokenRequest is the body of a request to create a Function run token.
+type runTokenRequest struct {
+	Payload   any   `json:"payload"`
+	Expiry    int64 `json:"expiry,omitempty"`
+	SingleUse bool  `json:"singleUse,omitempty"`
 }
 
 // Passing the name of your Evervault Function along with the data to be sent to that function will
 // return a RunTokenResponse. This response contains a token that can be returned to your
 // client for Function invocation.
 func (c *Client) CreateFunctionRunToken(functionName string, payload any) (RunTokenResponse, error) {
-	tokenResponse, err := c.createRunToken(functionName, payload)
+	tokenResponse, err := c.createRunToken(functionName, runTokenRequest{Payload: payload})
 	if err != nil {
 		return RunTokenResponse{}, err
 	}
@@ -24,6 +44,37 @@ func (c *Client) CreateFunctionRunToken(functionName string, payload any) (RunTo
 	return tokenResponse, nil
 }
 
+// CreateFunctionRunTokenWithOptions creates a Function run token like CreateFunctionRunToken, with an expiry or
+// limited to a single run. The payload is required and the token can only be used to run the Function with it.
+//
+//	token, err := evClient.CreateFunctionRunTokenWithOptions("hello-function", payload, evervault.RunTokenOptions{
+//		Expiry:    time.Now().Add(5 * time.Minute),
+//		SingleUse: true,
+//	})
+//
+// If the payload is nil ErrInvalidDataType is returned, if the expiry is in the past ErrInvalidTokenExpiry is
+// returned.
+func (c *Client) CreateFunctionRunTokenWithOptions(
+	functionName string,
+	payload any,
+	options RunTokenOptions,
+) (RunTokenResponse, error) {
+	if payload == nil {
+		return RunTokenResponse{}, ErrInvalidDataType
+	}
+
+	if !options.Expiry.IsZero() && !options.Expiry.After(time.Now()) {
+		return RunTokenResponse{}, ErrInvalidTokenExpiry
+	}
+
+	body := runTokenRequest{Payload: payload, SingleUse: options.SingleUse}
+	if !options.Expiry.IsZero() {
+		body.Expiry = options.Expiry.UnixMilli()
+	}
+
+	return c.createRunToken(functionName, body)
+}
+
 // Response containing the results of a Function run.
 // - FunctionRunResponse.Status contains the status of the function invocation (success/failure).
 // - FunctionRunResponse.ID contains the run ID of the function invocation.
@@ -34,36 +85,178 @@ type FunctionRunResponse struct {
 	Result map[string]any `json:"result"`
 }
 
+// RunMeta describes a Function run started with RunFunctionTyped.
+// - RunMeta.Status contains the status of the function invocation (success/failure).
+// - RunMeta.ID contains the run ID of the function invocation.
+type RunMeta struct {
+	Status string
+	ID     string
+}
+
+// functionRun is a Function run response with the result left encoded.
+type functionRun struct {
+	Status string          `json:"status"`
+	ID     string          `json:"id"`
+	Result json.RawMessage `json:"result"`
+}
+
+// Statuses of a Function run.
+const (
+	functionRunSuccess = "success"
+	functionRunFailure = "failure"
+	functionRunPending = "pending"
+	functionRunRunning = "running"
+)
+
 // Passing the name of your Evervault Function along with the data to be sent to that
 // function will invoke a function in your Evervault App. The response from the function
 // will be returned as a FunctionRunResponse.
 func (c *Client) RunFunction(functionName string, payload map[string]any) (FunctionRunResponse, error) {
-	functionResponse, err := c.runFunction(functionName, payload)
+	run, err := c.runFunction(context.Background(), functionName, payload, false)
 	if err != nil {
 		return FunctionRunResponse{}, err
 	}
 
-	return functionResponse, nil
+	var result map[string]any
+	if err := decodeFunctionResult(run.Result, &result); err != nil {
+		return FunctionRunResponse{}, err
+	}
+
+	return FunctionRunResponse{Status: run.Status, ID: run.ID, Result: result}, nil
 }
 
-func (c *Client) createRunToken(functionName string, payload any) (RunTokenResponse, error) {
-	pBytes, err := json.Marshal(payload)
+// RunFunctionTyped invokes a Function like RunFunction, encoding any JSON serialisable payload and decoding the
+// result of the Function into Out.
+//
+//	type Input struct {
+//		Name string `json:"name"`
+//	}
+//
+//	type Output struct {
+//		Message string `json:"message"`
+//	}
+//
+//	output, meta, err := evervault.RunFunctionTyped[Input, Output](ctx, evClient, "hello-function", Input{Name: "John"})
+//
+// Errors from the Function run are returned as a FunctionRuntimeError, FunctionTimeoutError or
+// FunctionNotReadyError like RunFunction.
+func RunFunctionTyped[In, Out any](ctx context.Context, c *Client, functionName string, in In) (Out, RunMeta, error) {
+	var out Out
+
+	run, err := c.runFunction(ctx, functionName, in, false)
 	if err != nil {
-		return RunTokenResponse{}, fmt.Errorf("error parsing payload as json %w", err)
+		return out, RunMeta{}, err
 	}
 
-	runTokenURL := fmt.Sprintf("%s/v2/functions/%s/run-token", c.Config.EvAPIURL, functionName)
+	meta := RunMeta{Status: run.Status, ID: run.ID}
+
+	if err := decodeFunctionResult(run.Result, &out); err != nil {
+		return out, meta, err
+	}
 
-	response, err := c.makeRequest(runTokenURL, http.MethodPost, pBytes, false)
+	return out, meta, nil
+}
 
-	if response.statusCode != http.StatusOK {
-		return RunTokenResponse{}, APIError{Message: "Error making HTTP request"}
+// decodeFunctionResult decodes the result of a Function run, leaving out unchanged if there is no result.
+func decodeFunctionResult(result json.RawMessage, out any) error {
+	if len(result) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(result, out); err != nil {
+		return fmt.Errorf("error parsing function result %w", err)
 	}
 
+	return nil
+}
+
+// StartFunctionRun invokes a Function asynchronously, returning as soon as the run has been scheduled. The returned
+// FunctionRunResponse.ID can be passed to GetFunctionRun or WaitFunctionRun to get the result of the run. If the
+// run already finished, FunctionRunResponse.Result is set as by RunFunction.
+//
+//	run, err := evClient.StartFunctionRun("hello-function", map[string]any{"name": "John"})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	result, err := evClient.WaitFunctionRun(ctx, "hello-function", run.ID)
+func (c *Client) StartFunctionRun(functionName string, payload map[string]any) (FunctionRunResponse, error) {
+	run, err := c.runFunction(context.Background(), functionName, payload, true)
+	if err != nil {
+		return FunctionRunResponse{}, err
+	}
+
+	var result map[string]any
+	if err := decodeFunctionResult(run.Result, &result); err != nil {
+		return FunctionRunResponse{}, err
+	}
+
+	return FunctionRunResponse{Status: run.Status, ID: run.ID, Result: result}, nil
+}
+
+// GetFunctionRun returns the current state of a run of the named Function started with StartFunctionRun. The
+// result is only set once FunctionRunResponse.Status is "success", otherwise the status is "pending" or "running".
+// If the run failed a FunctionRuntimeError is returned, and ErrUnknownFunctionRunStatus for any other status.
+func (c *Client) GetFunctionRun(functionName, runID string) (FunctionRunResponse, error) {
+	return c.getFunctionRun(context.Background(), functionName, runID)
+}
+
+// WaitFunctionRun polls a run of the named Function started with StartFunctionRun every
+// Config.FunctionRunPollingInterval until it finishes or ctx is done. If the run failed a FunctionRuntimeError is
+// returned, and ErrUnknownFunctionRunStatus if the run is neither finished, pending nor running.
+func (c *Client) WaitFunctionRun(ctx context.Context, functionName, runID string) (FunctionRunResponse, error) {
+	for {
+		run, err := c.getFunctionRun(ctx, functionName, runID)
+		if err != nil || run.Status == functionRunSuccess {
+			return run, err
+		}
+
+		if err := sleepContext(ctx, c.Config.FunctionRunPollingInterval); err != nil {
+			return FunctionRunResponse{}, fmt.Errorf("error waiting for function run %s %w", runID, err)
+		}
+	}
+}
+
+func (c *Client) getFunctionRun(ctx context.Context, functionName, runID string) (FunctionRunResponse, error) {
+	apiURL := fmt.Sprintf("%s/functions/%s/runs/%s", c.Config.EvAPIURL, url.PathEscape(functionName),
+		url.PathEscape(runID))
+
+	response, err := c.makeRequest(ctx, apiURL, http.MethodGet, nil, true)
+	if err != nil {
+		return FunctionRunResponse{}, err
+	}
+
+	run, err := parseFunctionRun(response, true)
+	if err != nil {
+		return FunctionRunResponse{}, err
+	}
+
+	var result map[string]any
+	if err := decodeFunctionResult(run.Result, &result); err != nil {
+		return FunctionRunResponse{}, err
+	}
+
+	return FunctionRunResponse{Status: run.Status, ID: run.ID, Result: result}, nil
+}
+
+// createRunToken creates a Function run token for the payload and options of the body.
+func (c *Client) createRunToken(functionName string, body runTokenRequest) (RunTokenResponse, error) {
+	pBytes, err := json.Marshal(body)
+	if err != nil {
+		return RunTokenResponse{}, fmt.Errorf("error parsing payload as json %w", err)
+	}
+
+	runTokenURL := fmt.Sprintf("%s/v2/functions/%s/run-token", c.Config.EvAPIURL, functionName)
+
+	response, err := c.makeRequest(context.Background(), runTokenURL, http.MethodPost, pBytes, false)
 	if err != nil {
 		return RunTokenResponse{}, err
 	}
 
+	if response.statusCode != http.StatusOK {
+		return RunTokenResponse{}, extractAPIError(response)
+	}
+
 	res := RunTokenResponse{}
 	if err := json.Unmarshal(response.body, &res); err != nil {
 		return RunTokenResponse{}, fmt.Errorf("error parsing JSON response %w", err)
@@ -72,34 +265,57 @@ func (c *Client) createRunToken(functionName string, payload any) (RunTokenRespo
 	return res, nil
 }
 
-func (c *Client) runFunction(functionName string, payload map[string]any) (FunctionRunResponse, error) {
+func (c *Client) runFunction(
+	ctx context.Context,
+	functionName string,
+	payload any,
+	async bool,
+) (result functionRun, err error) {
+	ctx, span := c.tracer().Start(ctx, OperationFunctionRun, map[string]string{"function": functionName})
+	defer func() { span.End(err) }()
+
 	wrappedPayload := map[string]any{"payload": payload}
+	if async {
+		wrappedPayload["async"] = true
+	}
 
 	pBytes, err := json.Marshal(wrappedPayload)
 	if err != nil {
-		return FunctionRunResponse{}, fmt.Errorf("error parsing payload as json %w", err)
+		return functionRun{}, fmt.Errorf("error parsing payload as json %w", err)
 	}
 
 	apiURL := fmt.Sprintf("%s/functions/%s/runs", c.Config.EvAPIURL, functionName)
 
-	response, err := c.makeRequest(apiURL, http.MethodPost, pBytes, true)
+	response, err := c.makeRequest(ctx, apiURL, http.MethodPost, pBytes, true)
 	if err != nil {
-		return FunctionRunResponse{}, err
+		return functionRun{}, err
 	}
 
-	functionRunResponse := FunctionRunResponse{}
-	err = json.Unmarshal(response.body, &functionRunResponse)
+	return parseFunctionRun(response, async)
+}
+
+// parseFunctionRun parses a Function run response, returning a FunctionRuntimeError if the run failed. Pending and
+// running runs are only accepted if pending is set.
+func parseFunctionRun(response clientResponse, pending bool) (functionRun, error) {
+	run := functionRun{}
+	err := json.Unmarshal(response.body, &run)
 
-	if err == nil && functionRunResponse.Status == "success" {
-		return functionRunResponse, nil
-	} else if err == nil && functionRunResponse.Status == "failure" {
+	if err == nil && run.Status == functionRunSuccess {
+		return run, nil
+	} else if err == nil && run.Status == functionRunFailure {
 		functionRuntimeError := FunctionRuntimeError{}
 
 		err = json.Unmarshal(response.body, &functionRuntimeError)
 		if err == nil {
-			return FunctionRunResponse{}, functionRuntimeError
+			return functionRun{}, functionRuntimeError
 		}
+	} else if err == nil && pending && run.ID != "" {
+		if run.Status == functionRunPending || run.Status == functionRunRunning {
+			return run, nil
+		}
+
+		return functionRun{}, fmt.Errorf("%w %q for run %s", ErrUnknownFunctionRunStatus, run.Status, run.ID)
 	}
 
-	return FunctionRunResponse{}, ExtractAPIError(response.body)
+	return functionRun{}, extractAPIError(response)
 }
@@ -4,8 +4,15 @@
 package evervault_test
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/evervault/evervault-go"
 	"github.com/stretchr/testify/assert"
@@ -25,10 +32,87 @@ func TestGetFunctionRunToken(t *testing.T) {
 	}
 
 	if res.Token != "test_token" {
-		t.Errorf("Expected encrypted string, got %s", res)
+		t.Errorf("Expected encrypted string, got %v", res)
 	}
 }
 
+func TestGetFunctionRunTokenWithOptions(t *testing.T) {
+	t.Parallel()
+
+	expiry := time.Now().Add(5 * time.Minute)
+
+	mockServer := startMockHTTPServer("", "")
+	defer mockServer.Close()
+
+	type runTokenBody struct {
+		Payload   map[string]any `json:"payload"`
+		Expiry    int64          `json:"expiry"`
+		SingleUse bool           `json:"singleUse"`
+	}
+
+	var (
+		mutex  sync.Mutex
+		bodies []runTokenBody
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/functions/test_function/run-token" {
+			mockServer.Config.Handler.ServeHTTP(writer, r)
+			return
+		}
+
+		var body runTokenBody
+
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+
+		if err := decoder.Decode(&body); err != nil || len(r.URL.Query()) > 0 {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		mutex.Lock()
+		bodies = append(bodies, body)
+		mutex.Unlock()
+
+		fmt.Fprintf(writer, `{"token": "test_token", "expiry": %d}`, expiry.UnixMilli())
+	}))
+	defer server.Close()
+
+	testClient := mockedClient(t, server)
+
+	res, err := testClient.CreateFunctionRunTokenWithOptions("test_function", map[string]any{"name": "john"},
+		evervault.RunTokenOptions{Expiry: expiry, SingleUse: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "test_token", res.Token)
+	assert.Equal(t, expiry.UnixMilli(), res.Expiry)
+
+	_, err = testClient.CreateFunctionRunToken("test_function", map[string]any{"name": "john"})
+	assert.NoError(t, err)
+
+	// Both entry points send the payload in the same body, with the options only if set.
+	payload := map[string]any{"name": "john"}
+	assert.Equal(t, []runTokenBody{
+		{Payload: payload, Expiry: expiry.UnixMilli(), SingleUse: true},
+		{Payload: payload},
+	}, bodies)
+}
+
+func TestGetFunctionRunTokenWithInvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	server := startMockHTTPServer("", "")
+	defer server.Close()
+	testClient := mockedClient(t, server)
+
+	_, err := testClient.CreateFunctionRunTokenWithOptions("test_function", nil, evervault.RunTokenOptions{})
+	assert.ErrorIs(t, err, evervault.ErrInvalidDataType)
+
+	_, err = testClient.CreateFunctionRunTokenWithOptions("test_function", "test_payload",
+		evervault.RunTokenOptions{Expiry: time.Now().Add(-time.Minute)})
+	assert.ErrorIs(t, err, evervault.ErrInvalidTokenExpiry)
+}
+
 func TestRunFunctionSuccess(t *testing.T) {
 	t.Parallel()
 
@@ -166,3 +250,238 @@ func TestRunFunctionUnauthorized(t *testing.T) {
 		assert.Equal(t, message, evervaultError.Message)
 	}
 }
+
+type greetingInput struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+type greetingOutput struct {
+	Message string `json:"message"`
+}
+
+func TestRunFunctionTypedSuccess(t *testing.T) {
+	t.Parallel()
+
+	message := "Hello from a Function! It seems you have 4 letters in your name"
+	id := "func_run_65bc5168cb8b"
+	functionResponsePayload := fmt.Sprintf(`
+	{
+		"status": "success",
+		"result": { "message": "%s" },
+		"id": "%s"
+	}`, message, id)
+
+	server := startMockHTTPServer(functionResponsePayload, "")
+	defer server.Close()
+
+	testClient := mockedClient(t, server)
+
+	res, meta, err := evervault.RunFunctionTyped[greetingInput, greetingOutput](
+		context.Background(), testClient, "test_function", greetingInput{Name: "john", Age: 30})
+	if err != nil {
+		t.Errorf("Failed to run Function, got %s", err)
+		return
+	}
+
+	assert.Equal(t, "success", meta.Status)
+	assert.Equal(t, id, meta.ID)
+	assert.Equal(t, message, res.Message)
+}
+
+func TestRunFunctionTypedFailure(t *testing.T) {
+	t.Parallel()
+
+	message := "Uh oh!"
+	id := "func_run_65bc5168cb8b"
+	functionResponsePayload := fmt.Sprintf(`
+	{
+		"status": "failure",
+		"error": { "message": "%s", "stack": "Error: Uh oh!..." },
+		"id": "%s"
+	}`, message, id)
+
+	server := startMockHTTPServer(functionResponsePayload, "")
+	defer server.Close()
+
+	testClient := mockedClient(t, server)
+
+	_, _, err := evervault.RunFunctionTyped[[]string, greetingOutput](
+		context.Background(), testClient, "test_function", []string{"john"})
+
+	var runtimeError evervault.FunctionRuntimeError
+	if assert.ErrorAs(t, err, &runtimeError) {
+		assert.Equal(t, message, runtimeError.ErrorBody.Message)
+		assert.Equal(t, id, runtimeError.ID)
+	}
+}
+
+func TestRunFunctionTypedResultMismatch(t *testing.T) {
+	t.Parallel()
+
+	functionResponsePayload := `{"status": "success", "result": { "message": 4 }, "id": "func_run_65bc5168cb8b"}`
+
+	server := startMockHTTPServer(functionResponsePayload, "")
+	defer server.Close()
+
+	testClient := mockedClient(t, server)
+
+	_, meta, err := evervault.RunFunctionTyped[map[string]any, greetingOutput](
+		context.Background(), testClient, "test_function", map[string]any{"name": "john"})
+	assert.Error(t, err)
+	assert.Equal(t, "func_run_65bc5168cb8b", meta.ID)
+}
+
+func asyncFunctionServer(t *testing.T, runs map[string][]string) *httptest.Server {
+	t.Helper()
+
+	mockServer := startMockHTTPServer("", "")
+	t.Cleanup(mockServer.Close)
+
+	var mutex sync.Mutex
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/functions/test_function/runs":
+			var body map[string]any
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["async"] != true {
+				writer.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			writer.Write([]byte(`{"id": "func_run_65bc5168cb8b", "status": "pending"}`))
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/functions/test_function/runs/"):
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			id := strings.TrimPrefix(r.URL.Path, "/functions/test_function/runs/")
+
+			responses, ok := runs[id]
+			if !ok {
+				writer.WriteHeader(http.StatusNotFound)
+				writer.Write([]byte(`{"status": 404, "code": "resource-not-found", "title": "Not Found"}`))
+
+				return
+			}
+
+			writer.Write([]byte(responses[0]))
+
+			if len(responses) > 1 {
+				runs[id] = responses[1:]
+			}
+		default:
+			mockServer.Config.Handler.ServeHTTP(writer, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestStartAndWaitFunctionRun(t *testing.T) {
+	t.Parallel()
+
+	id := "func_run_65bc5168cb8b"
+	server := asyncFunctionServer(t, map[string][]string{id: {
+		`{"id": "func_run_65bc5168cb8b", "status": "pending"}`,
+		`{"id": "func_run_65bc5168cb8b", "status": "running"}`,
+		`{"id": "func_run_65bc5168cb8b", "status": "success", "result": {"message": "done"}}`,
+	}})
+
+	testClient := mockedClient(t, server)
+	testClient.Config.FunctionRunPollingInterval = time.Millisecond
+
+	run, err := testClient.StartFunctionRun("test_function", map[string]any{"name": "john"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, id, run.ID)
+	assert.Equal(t, "pending", run.Status)
+
+	current, err := testClient.GetFunctionRun("test_function", id)
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", current.Status)
+	assert.Nil(t, current.Result)
+
+	result, err := testClient.WaitFunctionRun(context.Background(), "test_function", id)
+	assert.NoError(t, err)
+	assert.Equal(t, "success", result.Status)
+	assert.Equal(t, "done", result.Result["message"])
+}
+
+func TestWaitFunctionRunFailure(t *testing.T) {
+	t.Parallel()
+
+	id := "func_run_65bc5168cb8b"
+	server := asyncFunctionServer(t, map[string][]string{id: {
+		`{"id": "func_run_65bc5168cb8b", "status": "running"}`,
+		`{"id": "func_run_65bc5168cb8b", "status": "failure", "error": {"message": "Uh oh!", "stack": ""}}`,
+	}})
+
+	testClient := mockedClient(t, server)
+	testClient.Config.FunctionRunPollingInterval = time.Millisecond
+
+	_, err := testClient.WaitFunctionRun(context.Background(), "test_function", id)
+
+	var runtimeError evervault.FunctionRuntimeError
+	if assert.ErrorAs(t, err, &runtimeError) {
+		assert.Equal(t, "Uh oh!", runtimeError.ErrorBody.Message)
+	}
+}
+
+func TestWaitFunctionRunUnknownStatus(t *testing.T) {
+	t.Parallel()
+
+	id := "func_run_65bc5168cb8b"
+	server := asyncFunctionServer(t, map[string][]string{id: {
+		`{"id": "func_run_65bc5168cb8b", "status": "running"}`,
+		`{"id": "func_run_65bc5168cb8b", "status": "cancelled"}`,
+		`{"id": "func_run_65bc5168cb8b", "status": "success", "result": {"message": "done"}}`,
+	}})
+
+	testClient := mockedClient(t, server)
+	testClient.Config.FunctionRunPollingInterval = time.Millisecond
+
+	_, err := testClient.WaitFunctionRun(context.Background(), "test_function", id)
+	assert.ErrorIs(t, err, evervault.ErrUnknownFunctionRunStatus)
+}
+
+func TestStartFunctionRunFinishedSynchronously(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Write([]byte(`{"id": "func_run_65bc5168cb8b", "status": "success", "result": {"message": "done"}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	testClient := mockedClient(t, server)
+
+	run, err := testClient.StartFunctionRun("test_function", map[string]any{"name": "john"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "success", run.Status)
+		assert.Equal(t, "done", run.Result["message"])
+	}
+}
+
+func TestWaitFunctionRunContextDone(t *testing.T) {
+	t.Parallel()
+
+	id := "func_run_65bc5168cb8b"
+	server := asyncFunctionServer(t, map[string][]string{id: {`{"id": "func_run_65bc5168cb8b", "status": "running"}`}})
+
+	testClient := mockedClient(t, server)
+	testClient.Config.FunctionRunPollingInterval = time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := testClient.WaitFunctionRun(ctx, "test_function", id)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = testClient.GetFunctionRun("test_function", "func_run_missing")
+	assert.Error(t, err)
+
+	_, err = testClient.GetFunctionRun("other_function", id)
+	assert.Error(t, err)
+}
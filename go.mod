@@ -3,13 +3,13 @@ module github.com/evervault/evervault-go
 go 1.20
 
 require (
+	github.com/fxamacker/cbor/v2 v2.2.0
 	github.com/hf/nitrite v0.0.0-20211104000856-f9e0dcc73703
 	github.com/stretchr/testify v1.8.4
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/fxamacker/cbor/v2 v2.2.0 // indirect
 	github.com/jarcoal/httpmock v1.3.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/x448/float16 v0.8.4 // indirect
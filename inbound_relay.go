@@ -0,0 +1,260 @@
+package evervault
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// InboundRelayOption configures how InboundRelayHandler verifies requests.
+type InboundRelayOption func(*inboundRelayOptions)
+
+type inboundRelayOptions struct {
+	headers     map[string]string
+	clientCAs   *x509.CertPool
+	maxBodySize int64
+	unverified  bool
+	err         error
+}
+
+// defaultInboundRelayMaxBodySize is the largest JSON body read to find encrypted fields.
+const defaultInboundRelayMaxBodySize = 10 << 20
+
+// WithInboundRelayHeader requires every request to have a header with the given value, such as a secret header
+// added to requests by the Inbound Relay. It can be passed more than once to require several headers. The name and
+// value must not be empty, so an unset secret cannot disable verification.
+func WithInboundRelayHeader(name, value string) InboundRelayOption {
+	return func(o *inboundRelayOptions) {
+		if name == "" || value == "" {
+			o.err = fmt.Errorf("%w: Inbound Relay header %q must have a name and value", ErrInvalidConfig, name)
+			return
+		}
+
+		o.headers[http.CanonicalHeaderKey(name)] = value
+	}
+}
+
+// WithoutInboundRelayVerification passes every request to the handler without verifying it was received through
+// Inbound Relay, for services where requests are verified elsewhere such as by a load balancer terminating mutual
+// TLS. Only encrypted fields are found.
+func WithoutInboundRelayVerification() InboundRelayOption {
+	return func(o *inboundRelayOptions) {
+		o.unverified = true
+	}
+}
+
+// WithInboundRelayClientCAs requires every request to be made over a TLS connection on which the Inbound Relay
+// presented a client certificate issued by one of the CAs in pool. The server must request client certificates,
+// for example by setting tls.Config.ClientAuth to tls.RequestClientCert.
+func WithInboundRelayClientCAs(pool *x509.CertPool) InboundRelayOption {
+	return func(o *inboundRelayOptions) {
+		o.clientCAs = pool
+	}
+}
+
+// WithInboundRelayMaxBodySize sets the largest JSON body read to find encrypted fields, 10MB by default. Requests
+// with larger bodies are rejected with 413 Request Entity Too Large.
+func WithInboundRelayMaxBodySize(size int64) InboundRelayOption {
+	return func(o *inboundRelayOptions) {
+		o.maxBodySize = size
+	}
+}
+
+// EncryptedField is an Evervault encrypted value found in the JSON body of a request received through Inbound Relay.
+type EncryptedField struct {
+	// Path is the JSON Pointer (RFC 6901) of the value in the body, such as "/card/number" or "/items/0/ssn".
+	Path string
+	// Value is the encrypted string.
+	Value string
+}
+
+type encryptedFieldsKey struct{}
+
+// EncryptedFieldsFromContext returns the encrypted fields found by InboundRelayHandler in the body of the request
+// with the context, in the order they appear with object keys sorted. It returns nil if there are none.
+func EncryptedFieldsFromContext(ctx context.Context) []EncryptedField {
+	fields, _ := ctx.Value(encryptedFieldsKey{}).([]EncryptedField)
+	return fields
+}
+
+// InboundRelayHandler returns a http.Handler that verifies requests were received through Evervault Inbound Relay
+// before passing them to next. Requests that fail verification are rejected with 403 Forbidden.
+//
+// At least one of WithInboundRelayHeader, WithInboundRelayClientCAs or WithoutInboundRelayVerification must be
+// passed. An error wrapping ErrInvalidConfig is returned if none are, or a header is empty.
+//
+// Encrypted fields in JSON bodies are exposed to next with EncryptedFieldsFromContext, the body is left unchanged.
+//
+//	handler, err := evervault.InboundRelayHandler(mux,
+//		evervault.WithInboundRelayHeader("X-Relay-Secret", os.Getenv("RELAY_SECRET")),
+//	)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	mux.HandleFunc("/cards", func(w http.ResponseWriter, r *http.Request) {
+//		for _, field := range evervault.EncryptedFieldsFromContext(r.Context()) {
+//			log.Printf("encrypted field at %s", field.Path)
+//		}
+//	})
+func InboundRelayHandler(next http.Handler, opts ...InboundRelayOption) (http.Handler, error) {
+	options := inboundRelayOptions{headers: make(map[string]string), maxBodySize: defaultInboundRelayMaxBodySize}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if !options.verify(request) {
+			http.Error(writer, "request was not received through Evervault Inbound Relay", http.StatusForbidden)
+			return
+		}
+
+		if !isJSON(request.Header.Get("Content-Type")) || request.Body == nil {
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, options.maxBodySize))
+		request.Body.Close()
+
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(writer, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		if err != nil {
+			http.Error(writer, "could not read request body", http.StatusBadRequest)
+			return
+		}
+
+		request.Body = io.NopCloser(bytes.NewReader(body))
+
+		if fields := findEncryptedFields(body); len(fields) > 0 {
+			request = request.WithContext(context.WithValue(request.Context(), encryptedFieldsKey{}, fields))
+		}
+
+		next.ServeHTTP(writer, request)
+	}), nil
+}
+
+// validate checks the options verify requests, unless verification was explicitly disabled.
+func (o inboundRelayOptions) validate() error {
+	if o.err != nil {
+		return o.err
+	}
+
+	if len(o.headers) == 0 && o.clientCAs == nil && !o.unverified {
+		return fmt.Errorf("%w: Inbound Relay requests must be verified with a header or client CAs", ErrInvalidConfig)
+	}
+
+	return nil
+}
+
+// verify checks the request has every required header and a client certificate issued by the client CAs.
+func (o inboundRelayOptions) verify(request *http.Request) bool {
+	for name, expected := range o.headers {
+		actual := request.Header.Get(name)
+		if actual == "" || subtle.ConstantTimeCompare([]byte(actual), []byte(expected)) != 1 {
+			return false
+		}
+	}
+
+	if o.clientCAs == nil {
+		return true
+	}
+
+	if request.TLS == nil || len(request.TLS.PeerCertificates) == 0 {
+		return false
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range request.TLS.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := request.TLS.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         o.clientCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	return err == nil
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// findEncryptedFields returns the encrypted strings in a JSON document, nil if it is not valid JSON.
+func findEncryptedFields(body []byte) []EncryptedField {
+	var document any
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil
+	}
+
+	var fields []EncryptedField
+
+	walkJSON(document, "", func(path, value string) {
+		if isEncrypted(value) {
+			fields = append(fields, EncryptedField{Path: path, Value: value})
+		}
+	})
+
+	return fields
+}
+
+// walkJSON calls visit with the JSON Pointer and value of every string in a decoded JSON document.
+func walkJSON(value any, path string, visit func(path, value string)) {
+	switch typed := value.(type) {
+	case string:
+		visit(path, typed)
+	case []any:
+		for i, item := range typed {
+			walkJSON(item, path+"/"+strconv.Itoa(i), visit)
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		escaper := strings.NewReplacer("~", "~0", "/", "~1")
+		for _, key := range keys {
+			walkJSON(typed[key], path+"/"+escaper.Replace(key), visit)
+		}
+	}
+}
+
+// isEncrypted reports whether the value is in the Evervault encrypted string format,
+// "ev:<version>:[<datatype>:]<iv>:<public key>:<cipher text>:$".
+func isEncrypted(value string) bool {
+	if !strings.HasPrefix(value, "ev:") || !strings.HasSuffix(value, ":$") {
+		return false
+	}
+
+	parts := strings.Split(value, ":")
+
+	return len(parts) == 6 || len(parts) == 7
+}
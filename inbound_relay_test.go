@@ -0,0 +1,245 @@
+//go:build unit_test
+// +build unit_test
+
+package evervault_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evervault/evervault-go"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	encryptedString = "ev:QkTC:aXY:cHVibGlja2V5:Y2lwaGVydGV4dA:$"
+	encryptedNumber = "ev:QkTC:number:aXY:cHVibGlja2V5:Y2lwaGVydGV4dA:$"
+)
+
+type inboundRecorder struct {
+	called bool
+	fields []evervault.EncryptedField
+	body   string
+}
+
+func (r *inboundRecorder) ServeHTTP(_ http.ResponseWriter, request *http.Request) {
+	r.called = true
+	r.fields = evervault.EncryptedFieldsFromContext(request.Context())
+
+	body, _ := io.ReadAll(request.Body)
+	r.body = string(body)
+}
+
+func newInboundRelayHandler(t *testing.T, next http.Handler, opts ...evervault.InboundRelayOption) http.Handler {
+	t.Helper()
+
+	handler, err := evervault.InboundRelayHandler(next, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return handler
+}
+
+func TestInboundRelayHandlerFindsEncryptedFields(t *testing.T) {
+	t.Parallel()
+
+	body := `{"card": {"number": "` + encryptedString + `", "name": "plain"}, "items": [{"a/b": "` +
+		encryptedNumber + `"}, "ev:not-encrypted"]}`
+
+	recorder := &inboundRecorder{}
+	handler := newInboundRelayHandler(t, recorder, evervault.WithoutInboundRelayVerification())
+
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+
+	assert.True(t, recorder.called)
+	assert.Equal(t, body, recorder.body)
+	assert.Equal(t, []evervault.EncryptedField{
+		{Path: "/card/number", Value: encryptedString},
+		{Path: "/items/0/a~1b", Value: encryptedNumber},
+	}, recorder.fields)
+}
+
+func TestInboundRelayHandlerIgnoresNonJSON(t *testing.T) {
+	t.Parallel()
+
+	recorder := &inboundRecorder{}
+	handler := newInboundRelayHandler(t, recorder, evervault.WithoutInboundRelayVerification())
+
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(encryptedString))
+	request.Header.Set("Content-Type", "text/plain")
+
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	assert.True(t, recorder.called)
+	assert.Equal(t, encryptedString, recorder.body)
+	assert.Nil(t, recorder.fields)
+}
+
+func TestInboundRelayHandlerVerifiesHeaders(t *testing.T) {
+	t.Parallel()
+
+	recorder := &inboundRecorder{}
+	handler := newInboundRelayHandler(t, recorder, evervault.WithInboundRelayHeader("x-relay-secret", "secret"))
+
+	for secret, status := range map[string]int{"": http.StatusForbidden, "wrong": http.StatusForbidden} {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("X-Relay-Secret", secret)
+
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+
+		assert.Equal(t, status, response.Code)
+		assert.False(t, recorder.called)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("X-Relay-Secret", "secret")
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.True(t, recorder.called)
+}
+
+func TestInboundRelayHandlerRejectsMissingHeader(t *testing.T) {
+	t.Parallel()
+
+	recorder := &inboundRecorder{}
+	handler := newInboundRelayHandler(t, recorder, evervault.WithInboundRelayHeader("x-relay-secret", "secret"))
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusForbidden, response.Code)
+	assert.False(t, recorder.called)
+}
+
+func TestInboundRelayHandlerRejectsUnsetSecret(t *testing.T) {
+	t.Parallel()
+
+	// An unset environment variable must not disable verification.
+	for _, opt := range []evervault.InboundRelayOption{
+		evervault.WithInboundRelayHeader("X-Relay-Secret", os.Getenv("EV_TEST_UNSET_RELAY_SECRET")),
+		evervault.WithInboundRelayHeader("", "secret"),
+	} {
+		_, err := evervault.InboundRelayHandler(&inboundRecorder{}, opt)
+		assert.ErrorIs(t, err, evervault.ErrInvalidConfig)
+	}
+}
+
+func TestInboundRelayHandlerRequiresVerification(t *testing.T) {
+	t.Parallel()
+
+	_, err := evervault.InboundRelayHandler(&inboundRecorder{})
+	assert.ErrorIs(t, err, evervault.ErrInvalidConfig)
+
+	_, err = evervault.InboundRelayHandler(&inboundRecorder{}, evervault.WithInboundRelayMaxBodySize(8))
+	assert.ErrorIs(t, err, evervault.ErrInvalidConfig)
+}
+
+func TestInboundRelayHandlerVerifiesClientCertificate(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	cert := server.Certificate()
+
+	trusted := x509.NewCertPool()
+	trusted.AddCert(cert)
+
+	recorder := &inboundRecorder{}
+	handler := newInboundRelayHandler(t, recorder, evervault.WithInboundRelayClientCAs(trusted))
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+
+	assert.Equal(t, http.StatusForbidden, response.Code)
+
+	// The httptest certificate is not issued for client authentication.
+	request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+
+	assert.Equal(t, http.StatusForbidden, response.Code)
+	assert.False(t, recorder.called)
+
+	clientCert := clientCertificate(t)
+	trusted.AddCert(clientCert)
+
+	request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{clientCert}}
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.True(t, recorder.called)
+}
+
+func clientCertificate(t *testing.T) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "Inbound Relay"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+func TestInboundRelayHandlerLimitsBodySize(t *testing.T) {
+	t.Parallel()
+
+	recorder := &inboundRecorder{}
+	handler := newInboundRelayHandler(t, recorder, evervault.WithoutInboundRelayVerification(),
+		evervault.WithInboundRelayMaxBodySize(8))
+
+	request := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"key": "too large"}`))
+	request.Header.Set("Content-Type", "application/json")
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, response.Code)
+	assert.False(t, recorder.called)
+}
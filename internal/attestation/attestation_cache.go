@@ -2,54 +2,133 @@ package attestation
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"math"
 	"net/http"
 	"net/url"
+	"strconv"
 	"sync"
 	"time"
+
+	"github.com/evervault/evervault-go/internal/logging"
+	"github.com/evervault/evervault-go/internal/telemetry"
 )
 
+// errUnexpectedStatus is returned when an attestation doc is fetched with a response that is not successful.
+var errUnexpectedStatus = errors.New("unexpected attestation doc response status")
+
 type Cache struct {
 	cageURL  *url.URL
 	doc      []byte
 	mutex    sync.RWMutex
 	client   http.Client
 	ticker   *time.Ticker
-	stopPoll chan bool
+	cancel   context.CancelFunc
+	stopped  chan struct{}
+	stopOnce sync.Once
+	logger   logging.Logger
+	tracer   telemetry.StartFunc
+
+	fetchTimeout time.Duration
+	maxAttempts  int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+}
+
+// CacheOption configures a Cache.
+type CacheOption func(*Cache)
+
+// WithLogger sets the logger used to report failed attestation doc fetches.
+func WithLogger(logger logging.Logger) CacheOption {
+	return func(c *Cache) {
+		c.logger = logging.With(logger, "hostname", c.cageURL.Host)
+	}
 }
 
 const (
-	pollTimeout   = 30 * time.Second
-	maxRetries    = 3
-	retryInterval = 1 * time.Second
-	backoffFactor = 2
+	// DefaultFetchTimeout bounds the initial doc fetch and every background refresh, including retries.
+	DefaultFetchTimeout = 30 * time.Second
+	// DefaultMaxAttempts is the number of attempts made to fetch a doc.
+	DefaultMaxAttempts = 3
+	// DefaultBackoff is the delay before the first retry, multiplied by backoffFactor for every following retry.
+	DefaultBackoff = 2 * time.Second
+	backoffFactor  = 2
 )
 
-func NewAttestationCache(cageDomain string, pollingInterval time.Duration) (*Cache, error) {
+// WithFetchTimeout sets the timeout for the initial doc fetch and every background refresh, including retries.
+func WithFetchTimeout(timeout time.Duration) CacheOption {
+	return func(c *Cache) {
+		c.fetchTimeout = timeout
+	}
+}
+
+// WithRetries sets how many attempts are made to fetch a doc and the delay between them. The delay starts at
+// backoff and doubles after every attempt, up to maxBackoff unless it is zero.
+func WithRetries(maxAttempts int, backoff, maxBackoff time.Duration) CacheOption {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return func(c *Cache) {
+		c.maxAttempts = maxAttempts
+		c.backoff = backoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// WithRootCAs verifies the TLS certificate of the enclave against roots instead of the system roots when
+// fetching docs.
+func WithRootCAs(roots *x509.CertPool) CacheOption {
+	return func(c *Cache) {
+		c.client = http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12},
+		}}
+	}
+}
+
+// WithTracer sets the tracer notified of every attestation doc fetch.
+func WithTracer(tracer telemetry.StartFunc) CacheOption {
+	return func(c *Cache) {
+		c.tracer = tracer
+	}
+}
+
+func NewAttestationCache(cageDomain string, pollingInterval time.Duration, opts ...CacheOption) (*Cache, error) {
 	cageURL, err := url.Parse(fmt.Sprintf("https://%s/.well-known/attestation", cageDomain))
 	if err != nil {
 		return nil, fmt.Errorf("enclave attestation URL could not be parsed: %w", err)
 	}
 
+	pollCtx, cancelPoll := context.WithCancel(context.Background())
 	cache := &Cache{
-		cageURL:  cageURL,
-		doc:      make([]byte, 0),
-		mutex:    sync.RWMutex{},
-		client:   http.Client{},
-		ticker:   time.NewTicker(pollingInterval),
-		stopPoll: make(chan bool),
+		cageURL: cageURL,
+		doc:     make([]byte, 0),
+		mutex:   sync.RWMutex{},
+		client:  http.Client{},
+		ticker:  time.NewTicker(pollingInterval),
+		cancel:  cancelPoll,
+		stopped: make(chan struct{}),
+		logger:  logging.With(nil, "hostname", cageURL.Host),
+
+		fetchTimeout: DefaultFetchTimeout,
+		maxAttempts:  DefaultMaxAttempts,
+		backoff:      DefaultBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(cache)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), pollTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), cache.fetchTimeout)
 	defer cancel()
 
 	cache.LoadDoc(ctx)
 
-	go cache.pollAPI()
+	go cache.pollAPI(pollCtx)
 
 	return cache, nil
 }
@@ -67,8 +146,11 @@ func (c *Cache) Get() []byte {
 	return c.doc
 }
 
+// StopPolling stops refreshing the attestation doc in the background, cancelling any in flight request, and
+// waits for the poller to exit. It is safe to call more than once.
 func (c *Cache) StopPolling() {
-	c.stopPoll <- true
+	c.stopOnce.Do(c.cancel)
+	<-c.stopped
 }
 
 //nolint:tagliatelle
@@ -76,78 +158,141 @@ type CageDocResponse struct {
 	AttestationDoc string `json:"attestation_doc"`
 }
 
-func (c *Cache) getDoc(ctx context.Context) ([]byte, error) {
-	var lastErr error
+// GetChallengeDoc fetches a fresh attestation doc bound to the given nonce. The doc is not cached.
+func (c *Cache) GetChallengeDoc(ctx context.Context, nonce []byte) ([]byte, error) {
+	challengeURL := *c.cageURL
+	challengeURL.RawQuery = url.Values{"nonce": {base64.StdEncoding.EncodeToString(nonce)}}.Encode()
 
-	for attempt := 1; attempt <= maxRetries; attempt++ {
+	return c.getDoc(ctx, challengeURL.String())
+}
+
+func (c *Cache) getDoc(ctx context.Context, docURL string) (doc []byte, err error) {
+	var (
+		lastErr  error
+		attempts int
+	)
+
+	ctx, span := c.tracer.Start(ctx, telemetry.OperationAttestationDocFetch,
+		map[string]string{"hostname": c.cageURL.Host})
+	defer func() {
+		span.SetAttribute("attempts", strconv.Itoa(attempts))
+		span.End(err)
+	}()
+
+	for attempt := 1; attempt <= c.maxAttempts; attempt++ {
 		select {
 		case <-ctx.Done():
 			return nil, fmt.Errorf("context canceled or timed out: %w", ctx.Err())
 		default:
 			if attempt > 1 {
-				log.Printf("Attestation Document Cache: Attempt %d: Retrying in %v\n", attempt, retryInterval)
+				c.logger.Debug("retrying attestation doc fetch", "attempt", attempt)
 			}
 
-			req, reqErr := http.NewRequestWithContext(ctx, http.MethodGet, c.cageURL.String(), nil)
+			attempts = attempt
+
+			req, reqErr := http.NewRequestWithContext(ctx, http.MethodGet, docURL, nil)
 			if reqErr != nil {
 				return nil, fmt.Errorf("could not create request: %w", reqErr)
 			}
 
-			resp, respErr := c.client.Do(req)
-			if respErr != nil {
-				lastErr = c.handleError("could not get attestation doc", respErr, attempt)
-				continue
-			}
-			defer resp.Body.Close()
-
-			var response CageDocResponse
-			if decodeErr := json.NewDecoder(resp.Body).Decode(&response); decodeErr != nil {
-				lastErr = c.handleError("error decoding attestation doc JSON", decodeErr, attempt)
-				continue
-			}
-
-			docBytes, err := base64.StdEncoding.DecodeString(response.AttestationDoc)
-			if err == nil {
+			docBytes, message, fetchErr := c.fetchDoc(req)
+			if fetchErr == nil {
 				return docBytes, nil
 			}
 
-			lastErr = c.handleError("error decoding attestation doc", err, attempt)
+			lastErr = c.handleError(ctx, message, fetchErr, attempt)
 		}
 	}
 
-	return nil, fmt.Errorf("failed to get attestation doc after %d attempts: %w", maxRetries, lastErr)
+	return nil, fmt.Errorf("failed to get attestation doc after %d attempts: %w", attempts, lastErr)
 }
 
-func (c *Cache) handleError(message string, err error, attempt int) error {
-	log.Printf("Attempt %d %s: %v", attempt, message, err)
+// fetchDoc makes a single attempt to fetch an attestation doc. If it fails the message to log is returned with the
+// error.
+func (c *Cache) fetchDoc(req *http.Request) ([]byte, string, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, "could not get attestation doc", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, "unexpected attestation doc response", fmt.Errorf("%w %s", errUnexpectedStatus, resp.Status)
+	}
+
+	var response CageDocResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, "error decoding attestation doc JSON", err
+	}
+
+	docBytes, err := base64.StdEncoding.DecodeString(response.AttestationDoc)
+	if err != nil {
+		return nil, "error decoding attestation doc", err
+	}
+
+	return docBytes, "", nil
+}
+
+func (c *Cache) handleError(ctx context.Context, message string, err error, attempt int) error {
+	c.logger.Warn(message, "attempt", attempt, "error", err)
+
+	// There is nothing to wait for after the last attempt.
+	if attempt >= c.maxAttempts {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+
+	backoff := time.NewTimer(c.retryBackoff(attempt))
+	defer backoff.Stop()
 
-	backoffDuration := time.Duration(math.Pow(backoffFactor, float64(attempt))) * time.Second
-	time.Sleep(backoffDuration)
+	select {
+	case <-backoff.C:
+	case <-ctx.Done():
+	}
 
 	return fmt.Errorf("%s: %w", message, err)
 }
 
+// retryBackoff returns the delay before the retry following the given attempt.
+func (c *Cache) retryBackoff(attempt int) time.Duration {
+	backoff := c.backoff
+	for i := 1; i < attempt && (c.maxBackoff == 0 || backoff < c.maxBackoff); i++ {
+		backoff *= backoffFactor
+	}
+
+	if c.maxBackoff > 0 && backoff > c.maxBackoff {
+		return c.maxBackoff
+	}
+
+	return backoff
+}
+
 func (c *Cache) LoadDoc(ctx context.Context) {
-	docBytes, err := c.getDoc(ctx)
+	docBytes, err := c.getDoc(ctx, c.cageURL.String())
 	if err != nil {
-		log.Printf("Could not get attestation doc: %v", err)
+		c.logger.Error("could not load attestation doc", "error", err)
 		return
 	}
 
 	c.Set(docBytes)
 }
 
-func (c *Cache) pollAPI() {
+func (c *Cache) pollAPI(pollCtx context.Context) {
+	defer close(c.stopped)
+
 	for {
 		select {
 		case <-c.ticker.C:
-			ctx, cancel := context.WithTimeout(context.Background(), pollTimeout)
-			defer cancel()
-
-			c.LoadDoc(ctx)
-		case <-c.stopPoll:
+			c.poll(pollCtx)
+		case <-pollCtx.Done():
 			c.ticker.Stop()
 			return
 		}
 	}
 }
+
+func (c *Cache) poll(pollCtx context.Context) {
+	ctx, cancel := context.WithTimeout(pollCtx, c.fetchTimeout)
+	defer cancel()
+
+	c.LoadDoc(ctx)
+}
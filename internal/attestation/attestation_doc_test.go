@@ -4,7 +4,9 @@
 package attestation_test
 
 import (
+	"context"
 	"encoding/base64"
+	"fmt"
 	"net/http"
 	"testing"
 	"time"
@@ -64,3 +66,129 @@ func TestAttestationDocCachePoll(t *testing.T) {
 	assert.Contains(string(newDoc), string(newDecodedDoc))
 	cache.StopPolling()
 }
+
+func TestAttestationDocChallenge(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	assert := assert.New(t)
+
+	nonce := []byte("test-nonce")
+
+	responder := httpmock.Responder(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Query().Get("nonce") == base64.StdEncoding.EncodeToString(nonce) {
+			return httpmock.NewStringResponse(200, `{"attestation_doc": "Y2hhbGxlbmdl"}`), nil
+		}
+		return httpmock.NewStringResponse(200, `{"attestation_doc": "ZnJpZGF5"}`), nil
+	})
+
+	httpmock.RegisterResponder("GET", "https://test.app-133.cage.evervault.com/.well-known/attestation", responder)
+
+	cache, _ := attestation.NewAttestationCache("test.app-133.cage.evervault.com", time.Minute)
+
+	doc, err := cache.GetChallengeDoc(context.Background(), nonce)
+	assert.Nil(err)
+	assert.Equal("challenge", string(doc))
+
+	cachedDoc := cache.Get()
+	assert.Equal("friday", string(cachedDoc))
+	cache.StopPolling()
+}
+
+func TestAttestationDocCacheStopPollingTwice(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://test.app-133.cage.evervault.com/.well-known/attestation",
+		httpmock.NewStringResponder(200, `{"attestation_doc": "ZnJpZGF5"}`))
+
+	cache, _ := attestation.NewAttestationCache("test.app-133.cage.evervault.com", time.Minute)
+
+	cache.StopPolling()
+	cache.StopPolling()
+}
+
+func TestAttestationDocCacheRetries(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	assert := assert.New(t)
+
+	callCount := 0
+
+	responder := httpmock.Responder(func(req *http.Request) (*http.Response, error) {
+		callCount++
+		return httpmock.NewStringResponse(200, `not json`), nil
+	})
+
+	httpmock.RegisterResponder("GET", "https://test.app-133.cage.evervault.com/.well-known/attestation", responder)
+
+	start := time.Now()
+	cache, _ := attestation.NewAttestationCache("test.app-133.cage.evervault.com", time.Minute,
+		attestation.WithFetchTimeout(time.Second), attestation.WithRetries(2, time.Millisecond, 0))
+
+	assert.Equal(2, callCount)
+	assert.Less(time.Since(start), time.Second)
+	assert.Empty(cache.Get())
+	cache.StopPolling()
+}
+
+func TestAttestationDocCacheRetriesUnsuccessfulStatus(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	assert := assert.New(t)
+
+	callCount := 0
+
+	responder := httpmock.Responder(func(req *http.Request) (*http.Response, error) {
+		callCount++
+		if callCount == 1 {
+			return httpmock.NewStringResponse(http.StatusServiceUnavailable, `<html>Service Unavailable</html>`), nil
+		}
+
+		return httpmock.NewStringResponse(200, `{"attestation_doc": "ZnJpZGF5"}`), nil
+	})
+
+	httpmock.RegisterResponder("GET", "https://test.app-133.cage.evervault.com/.well-known/attestation", responder)
+
+	logger := &recordingLogger{}
+
+	cache, _ := attestation.NewAttestationCache("test.app-133.cage.evervault.com", time.Minute,
+		attestation.WithLogger(logger), attestation.WithRetries(2, time.Millisecond, 0))
+	defer cache.StopPolling()
+
+	assert.Equal(2, callCount)
+	assert.Equal([]byte("friday"), cache.Get())
+
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	if assert.NotEmpty(logger.entries) {
+		assert.Equal("unexpected attestation doc response", logger.entries[0].msg)
+		assert.Contains(logger.entries[0].args, "attempt")
+		assert.Contains(fmt.Sprint(logger.entries[0].args...), "503")
+	}
+}
+
+func TestAttestationDocCacheFetchTimeout(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	assert := assert.New(t)
+
+	responder := httpmock.Responder(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+
+	httpmock.RegisterResponder("GET", "https://test.app-133.cage.evervault.com/.well-known/attestation", responder)
+
+	start := time.Now()
+	cache, _ := attestation.NewAttestationCache("test.app-133.cage.evervault.com", time.Minute,
+		attestation.WithFetchTimeout(50*time.Millisecond))
+
+	assert.Less(time.Since(start), time.Second)
+	assert.Empty(cache.Get())
+	cache.StopPolling()
+}
@@ -0,0 +1,71 @@
+//go:build unit_test
+// +build unit_test
+
+package attestation_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/evervault/evervault-go/internal/attestation"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+type logEntry struct {
+	level string
+	msg   string
+	args  []any
+}
+
+type recordingLogger struct {
+	mutex   sync.Mutex
+	entries []logEntry
+}
+
+func (l *recordingLogger) record(level, msg string, args []any) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.entries = append(l.entries, logEntry{level, msg, args})
+}
+
+func (l *recordingLogger) Debug(msg string, args ...any) { l.record("DEBUG", msg, args) }
+func (l *recordingLogger) Info(msg string, args ...any)  { l.record("INFO", msg, args) }
+func (l *recordingLogger) Warn(msg string, args ...any)  { l.record("WARN", msg, args) }
+func (l *recordingLogger) Error(msg string, args ...any) { l.record("ERROR", msg, args) }
+
+func TestAttestationDocCacheLogsWithStructuredFields(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	assert := assert.New(t)
+
+	httpmock.RegisterResponder("GET", "https://test.app-133.cage.evervault.com/.well-known/attestation",
+		httpmock.NewStringResponder(200, `{"attestation_doc": "ZnJpZGF5"}`))
+
+	logger := &recordingLogger{}
+
+	cache, _ := attestation.NewAttestationCache("test.app-133.cage.evervault.com", time.Minute,
+		attestation.WithLogger(logger))
+	defer cache.StopPolling()
+
+	httpmock.RegisterResponder("GET", "https://test.app-133.cage.evervault.com/.well-known/attestation",
+		httpmock.NewStringResponder(200, `not json`))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := cache.GetChallengeDoc(ctx, []byte("nonce"))
+	assert.NotNil(err)
+
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	assert.NotEmpty(logger.entries)
+	assert.Equal("WARN", logger.entries[0].level)
+	assert.Equal("error decoding attestation doc JSON", logger.entries[0].msg)
+	assert.Equal([]any{"hostname", "test.app-133.cage.evervault.com", "attempt", 1}, logger.entries[0].args[:4])
+}
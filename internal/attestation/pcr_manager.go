@@ -2,15 +2,18 @@ package attestation
 
 import (
 	"context"
-	"log"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/evervault/evervault-go/attestation"
+	"github.com/evervault/evervault-go/internal/logging"
+	"github.com/evervault/evervault-go/internal/telemetry"
 )
 
 type PCRManager interface {
 	Get() *[]attestation.PCRs
+	StopPolling()
 }
 
 type StaticProvider struct {
@@ -23,10 +26,14 @@ type PollingProvider struct {
 	mutex   sync.RWMutex
 	ticker  *time.Ticker
 	cancel  context.CancelFunc
+	logger  logging.Logger
+	tracer  telemetry.StartFunc
 }
 
 func NewPollingPCRManager(pollingInterval time.Duration,
 	getPcrs func() ([]attestation.PCRs, error),
+	logger logging.Logger,
+	tracer telemetry.StartFunc,
 ) *PollingProvider {
 	emptyPCRs := []attestation.PCRs{}
 	ctx, cancel := context.WithCancel(context.Background())
@@ -36,6 +43,8 @@ func NewPollingPCRManager(pollingInterval time.Duration,
 		mutex:   sync.RWMutex{},
 		ticker:  time.NewTicker(pollingInterval),
 		cancel:  cancel,
+		logger:  logging.OrDefault(logger),
+		tracer:  tracer,
 	}
 
 	cache.load()
@@ -68,6 +77,10 @@ func (c *StaticProvider) Get() *[]attestation.PCRs {
 	return c.pcrs
 }
 
+// StopPolling is a no-op as static PCRs are never refreshed.
+func (c *StaticProvider) StopPolling() {}
+
+// StopPolling stops refreshing the PCRs in the background. It is safe to call more than once.
 func (c *PollingProvider) StopPolling() {
 	c.cancel()
 }
@@ -75,22 +88,31 @@ func (c *PollingProvider) StopPolling() {
 func (c *PollingProvider) load() {
 	pcrs, err := c.getPcrs()
 	if err != nil {
-		log.Printf("could not get pcrs doc: %v", err)
+		c.logger.Error("could not get PCRs", "error", err)
 	}
 
 	c.Set(&pcrs)
 }
 
+func (c *PollingProvider) refresh(ctx context.Context) {
+	_, span := c.tracer.Start(ctx, telemetry.OperationPCRRefresh, nil)
+
+	pcrs, err := c.getPcrs()
+	if err != nil {
+		c.logger.Error("could not refresh PCRs", "error", err)
+	}
+
+	span.SetAttribute("pcr_sets", strconv.Itoa(len(pcrs)))
+	span.End(err)
+
+	c.Set(&pcrs)
+}
+
 func (c *PollingProvider) pollAPI(ctx context.Context) {
 	for {
 		select {
 		case <-c.ticker.C:
-			pcrs, err := c.getPcrs()
-			if err != nil {
-				log.Printf("couldn't get pcrs: %v", err)
-			}
-
-			c.Set(&pcrs)
+			c.refresh(ctx)
 		case <-ctx.Done():
 			c.ticker.Stop()
 			return
@@ -0,0 +1,132 @@
+package attestation
+
+import (
+	"crypto/x509"
+	"sync"
+	"time"
+)
+
+// Registry de-duplicates attestation doc caches so that every client attesting the same enclave with the same
+// settings shares a single cache and polling goroutine.
+type Registry struct {
+	mutex   sync.Mutex
+	entries map[CacheKey]*registryEntry
+}
+
+// CacheKey identifies a cache in a Registry. Callers only share a cache when every field is equal, so a cache never
+// fetches docs with the trust roots, timeouts or retries of another caller. Trust roots are compared by pointer.
+type CacheKey struct {
+	Hostname        string
+	PollingInterval time.Duration
+	FetchTimeout    time.Duration
+	MaxAttempts     int
+	Backoff         time.Duration
+	MaxBackoff      time.Duration
+	RootCAs         *x509.CertPool
+	// Owner identifies the logger and tracer passed to Acquire, so fetches are reported to the caller that made
+	// them. It must be comparable.
+	Owner any
+}
+
+// options returns the CacheOption values that apply the settings of the key, zero settings use the defaults.
+func (k CacheKey) options() []CacheOption {
+	var opts []CacheOption
+
+	if k.FetchTimeout > 0 {
+		opts = append(opts, WithFetchTimeout(k.FetchTimeout))
+	}
+
+	if k.MaxAttempts > 0 {
+		opts = append(opts, WithRetries(k.MaxAttempts, k.Backoff, k.MaxBackoff))
+	}
+
+	if k.RootCAs != nil {
+		opts = append(opts, WithRootCAs(k.RootCAs))
+	}
+
+	return opts
+}
+
+type registryEntry struct {
+	cache *Cache
+	err   error
+	ready chan struct{}
+	refs  int
+}
+
+// DefaultRegistry is the process wide registry used by the Evervault client.
+var DefaultRegistry = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{entries: make(map[CacheKey]*registryEntry)}
+}
+
+// Acquire returns the attestation doc cache for the key, creating it if it is not in use. The returned release
+// function must be called once the cache is no longer needed, the cache stops polling once every caller has
+// released it. Release is safe to call more than once.
+//
+// The cache fetches docs with the settings of the key. The options set the logger and tracer identified by the
+// owner of the key, they are only used when the cache is created and later callers with the same key share the
+// existing cache.
+func (r *Registry) Acquire(key CacheKey, opts ...CacheOption) (*Cache, func(), error) {
+	r.mutex.Lock()
+
+	entry, ok := r.entries[key]
+	if ok {
+		entry.refs++
+		r.mutex.Unlock()
+		<-entry.ready
+	} else {
+		entry = &registryEntry{ready: make(chan struct{}), refs: 1}
+		r.entries[key] = entry
+		r.mutex.Unlock()
+
+		// The initial doc fetch can block, callers for the same key wait on ready instead of fetching.
+		entry.cache, entry.err = NewAttestationCache(key.Hostname, key.PollingInterval,
+			append(key.options(), opts...)...)
+		close(entry.ready)
+	}
+
+	if entry.err != nil {
+		r.release(key, entry)
+		return nil, nil, entry.err
+	}
+
+	var once sync.Once
+
+	release := func() {
+		once.Do(func() {
+			r.release(key, entry)
+		})
+	}
+
+	return entry.cache, release, nil
+}
+
+func (r *Registry) release(key CacheKey, entry *registryEntry) {
+	r.mutex.Lock()
+
+	entry.refs--
+	if entry.refs > 0 {
+		r.mutex.Unlock()
+		return
+	}
+
+	if r.entries[key] == entry {
+		delete(r.entries, key)
+	}
+
+	r.mutex.Unlock()
+
+	if entry.cache != nil {
+		entry.cache.StopPolling()
+	}
+}
+
+// Len returns the number of active caches.
+func (r *Registry) Len() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return len(r.entries)
+}
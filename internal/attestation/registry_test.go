@@ -0,0 +1,129 @@
+//go:build unit_test
+// +build unit_test
+
+package attestation_test
+
+import (
+	"crypto/x509"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/evervault/evervault-go/internal/attestation"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistrySharesCachePerHostname(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	assert := assert.New(t)
+
+	httpmock.RegisterResponder("GET", "https://test.app-133.cage.evervault.com/.well-known/attestation",
+		httpmock.NewStringResponder(200, `{"attestation_doc": "ZnJpZGF5"}`))
+	httpmock.RegisterResponder("GET", "https://other.app-133.cage.evervault.com/.well-known/attestation",
+		httpmock.NewStringResponder(200, `{"attestation_doc": "bW9uZGF5"}`))
+
+	registry := attestation.NewRegistry()
+
+	var (
+		wg       sync.WaitGroup
+		caches   = make([]*attestation.Cache, 5)
+		releases = make([]func(), 5)
+	)
+
+	for i := range caches {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			cache, release, err := registry.Acquire(attestation.CacheKey{
+				Hostname:        "test.app-133.cage.evervault.com",
+				PollingInterval: time.Minute,
+			})
+			assert.Nil(err)
+
+			caches[i] = cache
+			releases[i] = release
+		}(i)
+	}
+
+	wg.Wait()
+
+	for _, cache := range caches {
+		assert.Same(caches[0], cache)
+	}
+
+	assert.Equal(1, httpmock.GetCallCountInfo()["GET https://test.app-133.cage.evervault.com/.well-known/attestation"])
+
+	other, releaseOther, err := registry.Acquire(attestation.CacheKey{
+		Hostname:        "other.app-133.cage.evervault.com",
+		PollingInterval: time.Minute,
+	})
+	assert.Nil(err)
+	assert.NotSame(caches[0], other)
+	assert.Equal(2, registry.Len())
+
+	releaseOther()
+	releaseOther()
+	assert.Equal(1, registry.Len())
+
+	for _, release := range releases {
+		release()
+	}
+
+	assert.Equal(0, registry.Len())
+}
+
+func TestRegistrySeparatesCachesBySettings(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	assert := assert.New(t)
+
+	httpmock.RegisterResponder("GET", "https://test.app-133.cage.evervault.com/.well-known/attestation",
+		httpmock.NewStringResponder(200, `{"attestation_doc": "ZnJpZGF5"}`))
+
+	registry := attestation.NewRegistry()
+
+	key := attestation.CacheKey{
+		Hostname:        "test.app-133.cage.evervault.com",
+		PollingInterval: time.Minute,
+		FetchTimeout:    time.Second,
+		MaxAttempts:     1,
+	}
+
+	cache, release, err := registry.Acquire(key)
+	assert.Nil(err)
+
+	defer release()
+
+	shared, releaseShared, err := registry.Acquire(key)
+	assert.Nil(err)
+	assert.Same(cache, shared)
+
+	releaseShared()
+
+	otherTimeout := key
+	otherTimeout.FetchTimeout = 2 * time.Second
+
+	otherRetries := key
+	otherRetries.MaxAttempts = 2
+
+	// Caches with root CAs use their own transport rather than the mock, so the fetch fails within the timeout.
+	otherRoots := key
+	otherRoots.RootCAs = x509.NewCertPool()
+
+	for _, other := range []attestation.CacheKey{otherTimeout, otherRetries, otherRoots} {
+		otherCache, releaseOther, err := registry.Acquire(other)
+		assert.Nil(err)
+		assert.NotSame(cache, otherCache)
+		assert.Equal(2, registry.Len())
+
+		releaseOther()
+	}
+
+	assert.Equal(1, registry.Len())
+}
@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -34,6 +36,9 @@ const (
 	binaryRepresentationOfFourByteUnsignedInteger = 0xce
 )
 
+// ErrInvalidPublicKey is returned when a public key is not a valid P-256 point.
+var ErrInvalidPublicKey = errors.New("invalid P-256 public key")
+
 // DeriveKDFAESKey derives an AES key using the given public key and shared ECDH secret.
 func DeriveKDFAESKey(publicKey, sharedECDHSecret []byte) ([]byte, error) {
 	padding := []byte{0x00, 0x00, 0x00, 0x01}
@@ -279,3 +284,16 @@ func base64EncodeStripped(s []byte) string {
 	encoded := base64.StdEncoding.EncodeToString(s)
 	return strings.TrimRight(encoded, "=")
 }
+
+// DecompressPublicKey converts a compressed P-256 public key to its uncompressed form.
+func DecompressPublicKey(compressed []byte) ([]byte, error) {
+	curve := elliptic.P256()
+
+	x, y := elliptic.UnmarshalCompressed(curve, compressed)
+	if x == nil {
+		return nil, ErrInvalidPublicKey
+	}
+
+	//nolint:staticcheck
+	return elliptic.Marshal(curve, x, y), nil
+}
@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Logger is the structured logger used across the SDK. Args are alternating key value pairs,
+// matching the signature of *slog.Logger.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// Std writes info, warning and error messages to the standard log package. Debug messages are dropped.
+type Std struct{}
+
+func (Std) Debug(string, ...any) {}
+
+func (Std) Info(msg string, args ...any) {
+	log.Print(format("INFO", msg, args))
+}
+
+func (Std) Warn(msg string, args ...any) {
+	log.Print(format("WARN", msg, args))
+}
+
+func (Std) Error(msg string, args ...any) {
+	log.Print(format("ERROR", msg, args))
+}
+
+// format renders a message as `LEVEL msg key=value ...`.
+func format(level, msg string, args []any) string {
+	var builder strings.Builder
+
+	builder.WriteString(level)
+	builder.WriteString(" ")
+	builder.WriteString(msg)
+
+	for i := 0; i < len(args); i += 2 {
+		if i+1 < len(args) {
+			fmt.Fprintf(&builder, " %v=%v", args[i], args[i+1])
+		} else {
+			fmt.Fprintf(&builder, " !BADKEY=%v", args[i])
+		}
+	}
+
+	return builder.String()
+}
+
+// Discard drops every message.
+type Discard struct{}
+
+func (Discard) Debug(string, ...any) {}
+
+func (Discard) Info(string, ...any) {}
+
+func (Discard) Warn(string, ...any) {}
+
+func (Discard) Error(string, ...any) {}
+
+// OrDefault returns the logger, or Std if it is nil.
+func OrDefault(logger Logger) Logger {
+	if logger == nil {
+		return Std{}
+	}
+
+	return logger
+}
+
+// With returns a Logger that adds args to every message.
+func With(logger Logger, args ...any) Logger {
+	return withArgs{logger: OrDefault(logger), args: args}
+}
+
+type withArgs struct {
+	logger Logger
+	args   []any
+}
+
+func (w withArgs) Debug(msg string, args ...any) {
+	w.logger.Debug(msg, append(w.args[:len(w.args):len(w.args)], args...)...)
+}
+
+func (w withArgs) Info(msg string, args ...any) {
+	w.logger.Info(msg, append(w.args[:len(w.args):len(w.args)], args...)...)
+}
+
+func (w withArgs) Warn(msg string, args ...any) {
+	w.logger.Warn(msg, append(w.args[:len(w.args):len(w.args)], args...)...)
+}
+
+func (w withArgs) Error(msg string, args ...any) {
+	w.logger.Error(msg, append(w.args[:len(w.args):len(w.args)], args...)...)
+}
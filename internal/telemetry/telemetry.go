@@ -0,0 +1,53 @@
+package telemetry
+
+import "context"
+
+// Operation names reported by the internal packages.
+const (
+	OperationAttestationDocFetch = "evervault.attestation.fetch"
+	OperationPCRRefresh          = "evervault.pcrs.refresh"
+)
+
+// Span records the outcome of a single operation.
+type Span interface {
+	SetAttribute(key, value string)
+	End(err error)
+}
+
+// StartFunc starts a Span for an operation. A nil StartFunc records nothing.
+type StartFunc func(ctx context.Context, operation string, attributes map[string]string) (context.Context, Span)
+
+// Start starts a Span for the operation, returning a no-op Span if f is nil.
+func (f StartFunc) Start(ctx context.Context, operation string, attributes map[string]string) (context.Context, Span) {
+	if f == nil {
+		return ctx, noopSpan{}
+	}
+
+	return f(ctx, operation, attributes)
+}
+
+// WithAttributes returns a StartFunc that adds attributes to every operation started by f.
+func WithAttributes(f StartFunc, attributes map[string]string) StartFunc {
+	if f == nil {
+		return nil
+	}
+
+	return func(ctx context.Context, operation string, operationAttributes map[string]string) (context.Context, Span) {
+		merged := make(map[string]string, len(attributes)+len(operationAttributes))
+		for key, value := range attributes {
+			merged[key] = value
+		}
+
+		for key, value := range operationAttributes {
+			merged[key] = value
+		}
+
+		return f(ctx, operation, merged)
+	}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, string) {}
+
+func (noopSpan) End(error) {}
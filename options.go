@@ -0,0 +1,170 @@
+package evervault
+
+import (
+	"crypto/ecdh"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/evervault/evervault-go/internal/crypto"
+)
+
+// Option configures a Client created with New. Options return an error wrapping ErrInvalidConfig if the
+// value they are given is malformed.
+type Option func(*Config) error
+
+// New creates a new Client for an Evervault App. Unlike MakeClient the configuration is not read from
+// environment variables, the Evervault defaults are used unless overridden with an Option.
+//
+//	evClient, err := evervault.New("<APP_UUID>", "<API_KEY>",
+//		evervault.WithAPIURL("https://api.evervault.com"),
+//		evervault.WithLogger(slog.Default()),
+//		evervault.WithRetryPolicy(evervault.RetryPolicy{MaxAttempts: 3, InitialBackoff: 100 * time.Millisecond}),
+//	)
+//
+// If an apiKey or appUUID is not passed then ErrAppCredentialsRequired is returned. If an Option is
+// malformed an error wrapping ErrInvalidConfig is returned.
+func New(appUUID, apiKey string, opts ...Option) (*Client, error) {
+	config := defaultConfig()
+
+	for _, opt := range opts {
+		if err := opt(&config); err != nil {
+			return nil, err
+		}
+	}
+
+	return MakeCustomClient(appUUID, apiKey, config)
+}
+
+// WithAPIURL sets the URL of the Evervault API.
+func WithAPIURL(apiURL string) Option {
+	return func(config *Config) error {
+		if err := validateURL("API URL", apiURL); err != nil {
+			return err
+		}
+
+		config.EvAPIURL = apiURL
+
+		return nil
+	}
+}
+
+// WithRelayURL sets the URL of Outbound Relay.
+func WithRelayURL(relayURL string) Option {
+	return func(config *Config) error {
+		if err := validateURL("Relay URL", relayURL); err != nil {
+			return err
+		}
+
+		config.RelayURL = relayURL
+
+		return nil
+	}
+}
+
+// WithCAURL sets the URL the Evervault CA certificate used by Outbound Relay is downloaded from.
+func WithCAURL(caURL string) Option {
+	return func(config *Config) error {
+		if err := validateURL("CA URL", caURL); err != nil {
+			return err
+		}
+
+		config.EvervaultCaURL = caURL
+
+		return nil
+	}
+}
+
+// WithCAFile reads the Evervault CA used by Outbound Relay from a PEM file instead of downloading it, for
+// environments without access to the Evervault CA URL.
+func WithCAFile(path string) Option {
+	return func(config *Config) error {
+		if path == "" {
+			return fmt.Errorf("%w: CA file path must not be empty", ErrInvalidConfig)
+		}
+
+		config.EvervaultCaPath = path
+
+		return nil
+	}
+}
+
+// WithHTTPClient sets the http.Client used for requests to the Evervault API.
+func WithHTTPClient(client *http.Client) Option {
+	return func(config *Config) error {
+		if client == nil {
+			return fmt.Errorf("%w: HTTP client must not be nil", ErrInvalidConfig)
+		}
+
+		config.HTTPClient = client
+
+		return nil
+	}
+}
+
+// WithLogger sets the Logger used to report background failures.
+func WithLogger(logger Logger) Option {
+	return func(config *Config) error {
+		config.Logger = logger
+		return nil
+	}
+}
+
+// WithInstrumentation sets the Instrumentation notified of every operation.
+func WithInstrumentation(instrumentation Instrumentation) Option {
+	return func(config *Config) error {
+		config.Instrumentation = instrumentation
+		return nil
+	}
+}
+
+// WithRetryPolicy sets how requests to the Evervault API are retried.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(config *Config) error {
+		if !policy.valid() {
+			return fmt.Errorf("%w: retry policy values must not be negative", ErrInvalidConfig)
+		}
+
+		config.RetryPolicy = policy
+
+		return nil
+	}
+}
+
+// WithAttestationPollingInterval sets how often attestation docs and PCRs are refreshed for Enclave clients.
+func WithAttestationPollingInterval(interval time.Duration) Option {
+	return func(config *Config) error {
+		if interval <= 0 {
+			return fmt.Errorf("%w: attestation polling interval must be positive, got %s", ErrInvalidConfig, interval)
+		}
+
+		config.AttestationPollingInterval = interval
+		config.CagesPollingInterval = interval
+
+		return nil
+	}
+}
+
+// WithAppPublicKey sets the P-256 public key of the App, as shown in the Evervault dashboard, so it is not
+// fetched from the Evervault API when the Client is created. Both compressed and uncompressed keys are accepted.
+func WithAppPublicKey(publicKey []byte) Option {
+	return func(config *Config) error {
+		uncompressed := publicKey
+		if len(publicKey) > 0 && publicKey[0] != 0x04 {
+			decompressed, err := crypto.DecompressPublicKey(publicKey)
+			if err != nil {
+				return fmt.Errorf("%w: app public key: %w", ErrInvalidConfig, err)
+			}
+
+			uncompressed = decompressed
+		}
+
+		if _, err := ecdh.P256().NewPublicKey(uncompressed); err != nil {
+			return fmt.Errorf("%w: app public key: %w", ErrInvalidConfig, err)
+		}
+
+		config.AppPublicKey = uncompressed
+
+		return nil
+	}
+}
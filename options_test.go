@@ -0,0 +1,251 @@
+//go:build unit_test
+// +build unit_test
+
+package evervault_test
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/evervault/evervault-go"
+	"github.com/evervault/evervault-go/internal/crypto"
+	"github.com/evervault/evervault-go/internal/datatypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRejectsInvalidURL(t *testing.T) {
+	t.Parallel()
+
+	_, err := evervault.New("test_app_uuid", "test_api_key", evervault.WithAPIURL("ftp://api.evervault.com"))
+	assert.ErrorIs(t, err, evervault.ErrInvalidConfig)
+
+	_, err = evervault.New("test_app_uuid", "test_api_key", evervault.WithRelayURL("relay.evervault.com"))
+	assert.ErrorIs(t, err, evervault.ErrInvalidConfig)
+}
+
+func TestNewRejectsInvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	_, err := evervault.New("test_app_uuid", "test_api_key", evervault.WithHTTPClient(nil))
+	assert.ErrorIs(t, err, evervault.ErrInvalidConfig)
+
+	_, err = evervault.New("test_app_uuid", "test_api_key", evervault.WithAttestationPollingInterval(0))
+	assert.ErrorIs(t, err, evervault.ErrInvalidConfig)
+
+	_, err = evervault.New("test_app_uuid", "test_api_key",
+		evervault.WithRetryPolicy(evervault.RetryPolicy{MaxAttempts: -1}))
+	assert.ErrorIs(t, err, evervault.ErrInvalidConfig)
+
+	_, err = evervault.New("test_app_uuid", "test_api_key", evervault.WithAppPublicKey([]byte("not a key")))
+	assert.ErrorIs(t, err, evervault.ErrInvalidConfig)
+}
+
+func TestNewWithAppPublicKeySkipsKeyFetch(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		writer.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey := key.PublicKey().Bytes()
+
+	for _, configuredKey := range [][]byte{publicKey, crypto.CompressPublicKey(publicKey)} {
+		client, err := evervault.New("test_app_uuid", "test_api_key",
+			evervault.WithAPIURL(server.URL),
+			evervault.WithAppPublicKey(configuredKey),
+		)
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		assert.Equal(t, publicKey, client.Config.AppPublicKey)
+
+		encrypted, err := client.EncryptString("plaintext")
+		assert.NoError(t, err)
+		assert.True(t, isValidEncryptedString(encrypted, datatypes.String))
+	}
+
+	assert.Equal(t, int32(0), requests.Load())
+}
+
+func TestNewRetriesUnavailableAPI(t *testing.T) {
+	t.Parallel()
+
+	mockServer := startMockHTTPServer(nil, "")
+	defer mockServer.Close()
+
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if requests.Add(1) < 3 {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		mockServer.Config.Handler.ServeHTTP(writer, request)
+	}))
+	defer server.Close()
+
+	_, err := evervault.New("test_app_uuid", "test_api_key",
+		evervault.WithAPIURL(server.URL),
+		evervault.WithHTTPClient(server.Client()),
+		evervault.WithRetryPolicy(evervault.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestNewDoesNotRetryByDefault(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	_, err := evervault.New("test_app_uuid", "test_api_key", evervault.WithAPIURL(server.URL))
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestRetryPolicySkipsUnavailableNonIdempotentRequests(t *testing.T) {
+	t.Parallel()
+
+	mockServer := startMockHTTPServer(nil, "")
+	defer mockServer.Close()
+
+	var decrypts atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/decrypt" {
+			decrypts.Add(1)
+			writer.WriteHeader(http.StatusGatewayTimeout)
+
+			return
+		}
+
+		mockServer.Config.Handler.ServeHTTP(writer, request)
+	}))
+	defer server.Close()
+
+	client, err := evervault.New("test_app_uuid", "test_api_key",
+		evervault.WithAPIURL(server.URL),
+		evervault.WithRetryPolicy(evervault.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.DecryptString("ev:encrypted")
+	assert.ErrorAs(t, err, &evervault.ServerError{})
+	assert.Equal(t, int32(1), decrypts.Load())
+}
+
+// rateLimitedServer responds to the first decrypt request with 429 and the Retry-After header, and to the following
+// requests with a decrypted value.
+func rateLimitedServer(t *testing.T, retryAfter string, decrypts *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	mockServer := startMockHTTPServer(nil, "")
+	t.Cleanup(mockServer.Close)
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/decrypt" {
+			if decrypts.Add(1) == 1 {
+				writer.Header().Set("Retry-After", retryAfter)
+				writer.WriteHeader(http.StatusTooManyRequests)
+
+				return
+			}
+
+			writer.Header().Set("Content-Type", "application/json")
+			writer.Write([]byte(`"plaintext"`))
+
+			return
+		}
+
+		mockServer.Config.Handler.ServeHTTP(writer, request)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestRetryPolicyWaitsForRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	for name, maxBackoff := range map[string]time.Duration{"unbounded": 0, "within max backoff": 2 * time.Second} {
+		var decrypts atomic.Int32
+
+		server := rateLimitedServer(t, "1", &decrypts)
+
+		client, err := evervault.New("test_app_uuid", "test_api_key",
+			evervault.WithAPIURL(server.URL),
+			evervault.WithRetryPolicy(evervault.RetryPolicy{
+				MaxAttempts:    2,
+				InitialBackoff: time.Millisecond,
+				MaxBackoff:     maxBackoff,
+			}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		start := time.Now()
+
+		decrypted, err := client.DecryptString("ev:encrypted")
+		assert.NoError(t, err, name)
+		assert.Equal(t, "plaintext", decrypted, name)
+		assert.Equal(t, int32(2), decrypts.Load(), name)
+		assert.GreaterOrEqual(t, time.Since(start), time.Second, name)
+	}
+}
+
+func TestRetryPolicyDoesNotWaitPastMaxBackoff(t *testing.T) {
+	t.Parallel()
+
+	var decrypts atomic.Int32
+
+	server := rateLimitedServer(t, "86400", &decrypts)
+
+	client, err := evervault.New("test_app_uuid", "test_api_key",
+		evervault.WithAPIURL(server.URL),
+		evervault.WithRetryPolicy(evervault.RetryPolicy{
+			MaxAttempts:    2,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Second,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+
+	_, err = client.DecryptString("ev:encrypted")
+
+	var rateLimitError evervault.RateLimitError
+	if assert.ErrorAs(t, err, &rateLimitError) {
+		assert.Equal(t, 24*time.Hour, rateLimitError.RetryAfter)
+	}
+
+	assert.Equal(t, int32(1), decrypts.Load())
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -1,13 +1,46 @@
 package evervault
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
 )
 
+// RelayOption configures which requests an Outbound Relay client sends through Relay.
+type RelayOption func(*relayOptions)
+
+type relayOptions struct {
+	domains      []string
+	fetchDomains bool
+}
+
+// WithDecryptionDomains only sends requests for the given domains through Outbound Relay, all other requests are
+// sent directly. A domain starting with "*." matches every subdomain and "*" matches every host. It overrides
+// Config.DecryptionDomains.
+func WithDecryptionDomains(domains ...string) RelayOption {
+	return func(o *relayOptions) {
+		o.domains = domains
+		o.fetchDomains = false
+	}
+}
+
+// WithFetchedDecryptionDomains only sends requests for the Outbound Relay destinations configured for the App in the
+// Evervault dashboard through Relay, all other requests are sent directly. The destinations are fetched from the
+// Evervault API when the client is created.
+func WithFetchedDecryptionDomains() RelayOption {
+	return func(o *relayOptions) {
+		o.fetchDomains = true
+	}
+}
+
 // Will return a http.Client that is configured to use the Evervault Relay as a proxy,
 // enabling decryption of data before it reaches the requests destination.
 //
@@ -19,59 +52,383 @@ import (
 //	}
 //
 //	resp, err := outboundRelayClient.Post("https://example.com/", "application/json", bytes.NewBuffer(payload))
-func (c *Client) OutboundRelayClient() (*http.Client, error) {
-	response, err := c.makeRequest(c.Config.EvervaultCaURL, http.MethodGet, nil, false)
+//
+// Every request is sent through Relay unless Config.DecryptionDomains is set or a RelayOption limits the
+// destinations, see WithDecryptionDomains and WithFetchedDecryptionDomains.
+func (c *Client) OutboundRelayClient(opts ...RelayOption) (*http.Client, error) {
+	return c.OutboundRelayClientWithTransport(nil, opts...)
+}
 
-	if response.statusCode != http.StatusOK {
-		return nil, APIError{Message: "Error making HTTP request"}
+// OutboundRelayClientWithTransport returns a http.Client like OutboundRelayClient, built on a clone of base so that
+// keep-alives, timeouts, HTTP/2 and connection pooling can be configured. The Relay proxy URL, Proxy-Authorization
+// header and Evervault CA are always set on the clone, requests that are not relayed use base directly. A nil base
+// uses the same transport as OutboundRelayClient, which disables keep-alives.
+//
+//	base := http.DefaultTransport.(*http.Transport).Clone()
+//	base.MaxIdleConnsPerHost = 10
+//	base.IdleConnTimeout = 90 * time.Second
+//
+//	outboundRelayClient, err := evClient.OutboundRelayClientWithTransport(base)
+func (c *Client) OutboundRelayClientWithTransport(base *http.Transport, opts ...RelayOption) (*http.Client, error) {
+	routes, err := c.relayRoutes(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	caCert, err := c.relayCA()
+	if err != nil {
+		return nil, err
 	}
 
+	transport, err := c.transport(caCert, base)
 	if err != nil {
 		return nil, err
 	}
 
-	return c.relayClient(response.body)
+	var direct http.RoundTripper = http.DefaultTransport
+	if base != nil {
+		direct = base
+	}
+
+	return &http.Client{Transport: routes.wrap(transport, direct)}, nil
 }
 
-func (c *Client) relayClient(caCert []byte) (*http.Client, error) {
-	transport, err := c.transport(caCert)
+// relayCA returns the Evervault CA used by Outbound Relay. It is read from Config.EvervaultCaPath if set, otherwise
+// it is downloaded from Config.EvervaultCaURL and cached for Config.EvervaultCaRefreshInterval. If the CA cannot be
+// refreshed the cached CA is used.
+func (c *Client) relayCA() ([]byte, error) {
+	if c.Config.EvervaultCaPath != "" {
+		caCert, err := os.ReadFile(c.Config.EvervaultCaPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading evervault CA %w", err)
+		}
+
+		return caCert, nil
+	}
+
+	c.caMutex.Lock()
+	defer c.caMutex.Unlock()
+
+	if c.caCert != nil && time.Since(c.caFetchedAt) < c.Config.EvervaultCaRefreshInterval {
+		return c.caCert, nil
+	}
+
+	caCert, err := c.downloadRelayCA()
+	if err != nil {
+		if c.caCert == nil {
+			return nil, err
+		}
+
+		c.logger().Warn("could not refresh evervault CA, using cached CA", "error", err)
+
+		return c.caCert, nil
+	}
+
+	c.caCert = caCert
+	c.caFetchedAt = time.Now()
+
+	return caCert, nil
+}
+
+// downloadRelayCA downloads the Evervault CA and checks that it can be parsed.
+func (c *Client) downloadRelayCA() ([]byte, error) {
+	response, err := c.makeRequest(context.Background(), c.Config.EvervaultCaURL, http.MethodGet, nil, false)
 	if err != nil {
 		return nil, err
 	}
 
-	return &http.Client{Transport: transport}, nil
+	if response.statusCode != http.StatusOK {
+		return nil, extractAPIError(response)
+	}
+
+	if !x509.NewCertPool().AppendCertsFromPEM(response.body) {
+		return nil, ErrInvalidCACertificate
+	}
+
+	return response.body, nil
 }
 
-func (c *Client) transport(caCert []byte) (*http.Transport, error) {
+// transport returns a clone of base that proxies requests through Outbound Relay and trusts the Evervault CA.
+func (c *Client) transport(caCert []byte, base *http.Transport) (*http.Transport, error) {
 	proxyURL, err := url.Parse(c.Config.RelayURL)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing relay URL %w", err)
 	}
 
-	tlsClientConfig, err := tlsConfig(caCert)
+	var transport *http.Transport
+	if base != nil {
+		transport = base.Clone()
+	} else {
+		transport = &http.Transport{DisableKeepAlives: true}
+	}
+
+	tlsClientConfig, err := tlsConfig(caCert, transport.TLSClientConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	return &http.Transport{
-		DisableKeepAlives:  true,
-		TLSClientConfig:    tlsClientConfig,
-		Proxy:              http.ProxyURL(proxyURL),
-		ProxyConnectHeader: http.Header{"Proxy-Authorization": []string{c.apiKey}},
-	}, nil
+	proxyConnectHeader := transport.ProxyConnectHeader.Clone()
+	if proxyConnectHeader == nil {
+		proxyConnectHeader = http.Header{}
+	}
+
+	proxyConnectHeader["Proxy-Authorization"] = []string{c.apiKey}
+
+	transport.TLSClientConfig = tlsClientConfig
+	transport.Proxy = http.ProxyURL(proxyURL)
+	transport.ProxyConnectHeader = proxyConnectHeader
+
+	return transport, nil
 }
 
-func tlsConfig(caCert []byte) (*tls.Config, error) {
-	rootCAs, err := x509.SystemCertPool()
+// tlsConfig returns a clone of base, or a new config if it is nil, that also trusts the Evervault CA.
+func tlsConfig(caCert []byte, base *tls.Config) (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: false,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if base != nil {
+		config = base.Clone()
+		if config.MinVersion < tls.VersionTLS12 {
+			config.MinVersion = tls.VersionTLS12
+		}
+	}
+
+	var rootCAs *x509.CertPool
+	if config.RootCAs != nil {
+		rootCAs = config.RootCAs.Clone()
+	} else {
+		systemCAs, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, fmt.Errorf("error getting system cert pool %w", err)
+		}
+
+		rootCAs = systemCAs
+	}
+
+	if !rootCAs.AppendCertsFromPEM(caCert) {
+		return nil, ErrInvalidCACertificate
+	}
+
+	config.RootCAs = rootCAs
+
+	return config, nil
+}
+
+// OutboundRelayTransport returns a http.RoundTripper that sends requests through Outbound Relay, for libraries that
+// accept a RoundTripper rather than a http.Client. Requests that are not relayed, see RelayOption, are sent using
+// http.DefaultTransport.
+//
+//	transport, err := evClient.OutboundRelayTransport()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	httpClient := &http.Client{Transport: instrumentedTransport(transport)}
+func (c *Client) OutboundRelayTransport(opts ...RelayOption) (http.RoundTripper, error) {
+	client, err := c.OutboundRelayClientWithTransport(nil, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("error getting system cert pool %w", err)
+		return nil, err
 	}
 
-	rootCAs.AppendCertsFromPEM(caCert)
+	return client.Transport, nil
+}
 
-	return &tls.Config{
-		InsecureSkipVerify: false,
-		RootCAs:            rootCAs,
-		MinVersion:         tls.VersionTLS12,
-	}, nil
+// WrapHTTPClient returns a copy of client that sends requests for the given hosts through Outbound Relay, while
+// every other request is sent directly using the transport of client. Hosts are matched case-insensitively and
+// without the port, a host starting with "*." matches every subdomain. If no hosts are given Config.DecryptionDomains
+// is used, and every request is sent through Outbound Relay if it is empty. A nil client wraps http.DefaultClient.
+//
+// Relayed requests are sent using a clone of the transport of client if it is an *http.Transport. Other transports,
+// such as instrumented RoundTrippers wrapping their own transport, are kept for direct requests and relayed requests
+// are sent using a clone of http.DefaultTransport.
+//
+//	httpClient, err := evClient.WrapHTTPClient(instrumentedClient, "api.example.com", "*.example.net")
+func (c *Client) WrapHTTPClient(client *http.Client, hosts ...string) (*http.Client, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	routes := newRelayRoutes(hosts)
+	if len(hosts) == 0 {
+		routes = newRelayRoutes(c.Config.DecryptionDomains)
+	}
+
+	direct := client.Transport
+	if direct == nil {
+		direct = http.DefaultTransport
+	}
+
+	caCert, err := c.relayCA()
+	if err != nil {
+		return nil, err
+	}
+
+	relay, err := c.transport(caCert, relayBase(direct))
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped := *client
+	wrapped.Transport = routes.wrap(relay, direct)
+
+	return &wrapped, nil
+}
+
+// relayBase returns the transport to clone for relayed requests, the direct transport if it is an *http.Transport
+// or else http.DefaultTransport.
+func relayBase(direct http.RoundTripper) *http.Transport {
+	if transport, ok := direct.(*http.Transport); ok {
+		return transport
+	}
+
+	if transport, ok := http.DefaultTransport.(*http.Transport); ok {
+		return transport
+	}
+
+	return nil
+}
+
+// relayRoutes returns the destinations to send through Relay, fetching them from the Evervault API if requested.
+func (c *Client) relayRoutes(opts []RelayOption) (relayRoutes, error) {
+	options := relayOptions{domains: c.Config.DecryptionDomains}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if !options.fetchDomains {
+		return newRelayRoutes(options.domains), nil
+	}
+
+	domains, err := c.fetchDecryptionDomains()
+	if err != nil {
+		return relayRoutes{}, err
+	}
+
+	return relayRoutes{domains: normalizeDomains(domains)}, nil
+}
+
+type relayOutboundDestination struct {
+	DestinationDomain string `json:"destinationDomain"`
+}
+
+type relayOutboundResponse struct {
+	OutboundDestinations map[string]relayOutboundDestination `json:"outboundDestinations"`
+}
+
+// fetchDecryptionDomains returns the Outbound Relay destinations configured for the App.
+func (c *Client) fetchDecryptionDomains() ([]string, error) {
+	relayOutboundURL := c.Config.EvAPIURL + "/v2/relay-outbound"
+
+	response, err := c.makeRequest(context.Background(), relayOutboundURL, http.MethodGet, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.statusCode != http.StatusOK {
+		return nil, extractAPIError(response)
+	}
+
+	res := relayOutboundResponse{}
+	if err := json.Unmarshal(response.body, &res); err != nil {
+		return nil, fmt.Errorf("error parsing JSON response %w", err)
+	}
+
+	domains := make([]string, 0, len(res.OutboundDestinations))
+	for _, destination := range res.OutboundDestinations {
+		domains = append(domains, destination.DestinationDomain)
+	}
+
+	sort.Strings(domains)
+
+	return domains, nil
+}
+
+// relayRoutes decides which hosts are sent through Relay.
+type relayRoutes struct {
+	all     bool
+	domains []string
+}
+
+// newRelayRoutes relays the given domains, or every host if there are none.
+func newRelayRoutes(domains []string) relayRoutes {
+	if len(domains) == 0 {
+		return relayRoutes{all: true}
+	}
+
+	return relayRoutes{domains: normalizeDomains(domains)}
+}
+
+func normalizeDomains(domains []string) []string {
+	normalized := make([]string, len(domains))
+	for i, domain := range domains {
+		normalized[i] = strings.ToLower(domain)
+	}
+
+	return normalized
+}
+
+func (r relayRoutes) relayed(host string) bool {
+	if r.all {
+		return true
+	}
+
+	host = strings.ToLower(host)
+	for _, domain := range r.domains {
+		if matchesDomain(host, domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesDomain reports whether the host matches the domain, where "*.example.com" matches every subdomain of
+// example.com and "*" matches every host.
+func matchesDomain(host, domain string) bool {
+	if domain == "*" {
+		return true
+	}
+
+	if strings.HasPrefix(domain, "*.") {
+		return strings.HasSuffix(host, domain[1:])
+	}
+
+	return host == domain
+}
+
+// wrap returns relay if every host is relayed, otherwise a transport routing between relay and direct.
+func (r relayRoutes) wrap(relay, direct http.RoundTripper) http.RoundTripper {
+	if r.all {
+		return relay
+	}
+
+	return &routingTransport{relay: relay, direct: direct, routes: r}
+}
+
+// routingTransport sends requests for the relayed hosts through Outbound Relay and all others directly.
+type routingTransport struct {
+	relay  http.RoundTripper
+	direct http.RoundTripper
+	routes relayRoutes
+}
+
+func (t *routingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.routes.relayed(req.URL.Hostname()) {
+		return t.relay.RoundTrip(req) //nolint:wrapcheck
+	}
+
+	return t.direct.RoundTrip(req) //nolint:wrapcheck
+}
+
+// CloseIdleConnections closes the idle connections of both transports, see http.Client.CloseIdleConnections.
+func (t *routingTransport) CloseIdleConnections() {
+	type closeIdler interface{ CloseIdleConnections() }
+
+	for _, transport := range []http.RoundTripper{t.relay, t.direct} {
+		if closer, ok := transport.(closeIdler); ok {
+			closer.CloseIdleConnections()
+		}
+	}
 }
@@ -6,10 +6,18 @@ package evervault_test
 import (
 	"context"
 	"encoding/json"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/evervault/evervault-go"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestOutboundClientRoutesToOutboundRelay(t *testing.T) {
@@ -57,3 +65,380 @@ func TestOutboundClientRoutesToOutboundRelay(t *testing.T) {
 
 	resp.Body.Close()
 }
+
+func TestOutboundRelayClientWithTransport(t *testing.T) {
+	t.Parallel()
+
+	server := startMockHTTPServer("", "")
+	defer server.Close()
+
+	testClient := mockedClient(t, server)
+
+	base := &http.Transport{
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     time.Minute,
+		ForceAttemptHTTP2:   true,
+		ProxyConnectHeader:  http.Header{"X-Custom": []string{"value"}},
+	}
+
+	relayClient, err := testClient.OutboundRelayClientWithTransport(base)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	transport, ok := relayClient.Transport.(*http.Transport)
+	if !assert.True(t, ok) {
+		return
+	}
+
+	assert.False(t, transport.DisableKeepAlives)
+	assert.Equal(t, 10, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, time.Minute, transport.IdleConnTimeout)
+	assert.True(t, transport.ForceAttemptHTTP2)
+	assert.Equal(t, "value", transport.ProxyConnectHeader.Get("X-Custom"))
+	assert.Equal(t, "test_app_uuid", transport.ProxyConnectHeader.Get("Proxy-Authorization"))
+	assert.NotNil(t, transport.TLSClientConfig.RootCAs)
+	assert.Nil(t, base.Proxy)
+	assert.Empty(t, base.ProxyConnectHeader.Get("Proxy-Authorization"))
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://testtarget.com/", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	proxyURL, err := transport.Proxy(req)
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL, proxyURL.String())
+}
+
+func TestWrapHTTPClientRoutesSelectedHosts(t *testing.T) {
+	t.Parallel()
+
+	var relayedHosts []string
+
+	mockRelayServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		relayedHosts = append(relayedHosts, r.URL.Host)
+		writer.WriteHeader(http.StatusOK)
+	}))
+	defer mockRelayServer.Close()
+
+	directRequests := 0
+
+	directServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		directRequests++
+		writer.WriteHeader(http.StatusOK)
+	}))
+	defer directServer.Close()
+
+	server := startMockHTTPServer("", "")
+	defer server.Close()
+
+	testClient := mockedClient(t, server)
+	testClient.Config.RelayURL = mockRelayServer.URL
+
+	httpClient := &http.Client{Timeout: time.Minute}
+
+	wrapped, err := testClient.WrapHTTPClient(httpClient, "Relayed.Example.com")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, time.Minute, wrapped.Timeout)
+	assert.Nil(t, httpClient.Transport)
+
+	for _, target := range []string{"http://relayed.example.com/", directServer.URL} {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, target, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		resp, err := wrapped.Do(req)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, []string{"relayed.example.com"}, relayedHosts)
+	assert.Equal(t, 1, directRequests)
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestWrapHTTPClientUsesClientTransport(t *testing.T) {
+	t.Parallel()
+
+	var relayed atomic.Int32
+
+	mockRelayServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		relayed.Add(1)
+		writer.WriteHeader(http.StatusOK)
+	}))
+	defer mockRelayServer.Close()
+
+	server := startMockHTTPServer("", "")
+	defer server.Close()
+
+	testClient := mockedClient(t, server)
+	testClient.Config.RelayURL = mockRelayServer.URL
+
+	var dials atomic.Int32
+
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dials.Add(1)
+
+		var dialer net.Dialer
+
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	wrapped, err := testClient.WrapHTTPClient(&http.Client{Transport: base}, "relayed.example.com")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	relayedRequests(t, wrapped, []string{"http://relayed.example.com/"})
+	assert.Equal(t, int32(1), relayed.Load())
+	assert.Equal(t, int32(1), dials.Load())
+
+	defaultWrapped, err := testClient.WrapHTTPClient(nil, "relayed.example.com")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	relayedRequests(t, defaultWrapped, []string{"http://relayed.example.com/"})
+	assert.Equal(t, int32(2), relayed.Load())
+
+}
+
+func TestWrapHTTPClientKeepsCustomRoundTripper(t *testing.T) {
+	t.Parallel()
+
+	var relayed, direct, instrumented atomic.Int32
+
+	mockRelayServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		relayed.Add(1)
+		writer.WriteHeader(http.StatusOK)
+	}))
+	defer mockRelayServer.Close()
+
+	directServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		direct.Add(1)
+		writer.WriteHeader(http.StatusOK)
+	}))
+	defer directServer.Close()
+
+	server := startMockHTTPServer("", "")
+	defer server.Close()
+
+	testClient := mockedClient(t, server)
+	testClient.Config.RelayURL = mockRelayServer.URL
+
+	// An instrumented client wraps its transport in a RoundTripper that is not an *http.Transport.
+	instrumentedClient := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		instrumented.Add(1)
+
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+
+	wrapped, err := testClient.WrapHTTPClient(instrumentedClient, "relayed.example.com")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	relayedRequests(t, wrapped, []string{"http://relayed.example.com/", directServer.URL})
+	assert.Equal(t, int32(1), relayed.Load())
+	assert.Equal(t, int32(1), direct.Load())
+	assert.Equal(t, int32(1), instrumented.Load())
+}
+
+func relayedRequests(t *testing.T, relayClient *http.Client, targets []string) {
+	t.Helper()
+
+	for _, target := range targets {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, target, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		resp, err := relayClient.Do(req)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		resp.Body.Close()
+	}
+}
+
+func TestOutboundRelayClientDecryptionDomains(t *testing.T) {
+	t.Parallel()
+
+	var relayedHosts []string
+
+	mockRelayServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		relayedHosts = append(relayedHosts, r.URL.Host)
+		writer.WriteHeader(http.StatusOK)
+	}))
+	defer mockRelayServer.Close()
+
+	directRequests := 0
+
+	directServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		directRequests++
+		writer.WriteHeader(http.StatusOK)
+	}))
+	defer directServer.Close()
+
+	server := startMockHTTPServer("", "")
+	defer server.Close()
+
+	testClient := mockedClient(t, server)
+	testClient.Config.RelayURL = mockRelayServer.URL
+	testClient.Config.DecryptionDomains = []string{"ignored.example.com"}
+
+	relayClient, err := testClient.OutboundRelayClient(
+		evervault.WithDecryptionDomains("exact.example.com", "*.Wildcard.example.com"),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	relayedRequests(t, relayClient, []string{
+		"http://exact.example.com/",
+		"http://api.wildcard.example.com/",
+		"http://a.b.wildcard.example.com/",
+		directServer.URL,
+	})
+
+	assert.Equal(t, []string{"exact.example.com", "api.wildcard.example.com", "a.b.wildcard.example.com"}, relayedHosts)
+	assert.Equal(t, 1, directRequests)
+}
+
+func TestOutboundRelayClientFetchedDecryptionDomains(t *testing.T) {
+	t.Parallel()
+
+	var relayedHosts []string
+
+	mockRelayServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		relayedHosts = append(relayedHosts, r.URL.Host)
+		writer.WriteHeader(http.StatusOK)
+	}))
+	defer mockRelayServer.Close()
+
+	mockServer := startMockHTTPServer("", "")
+	defer mockServer.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/relay-outbound" {
+			mockServer.Config.Handler.ServeHTTP(writer, r)
+			return
+		}
+
+		assert.Equal(t, "test_app_uuid", r.Header.Get("API-KEY"))
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write([]byte(`{"outboundDestinations": {
+			"a": {"destinationDomain": "*.relayed.com"},
+			"b": {"destinationDomain": "relayed.net"}
+		}}`))
+	}))
+	defer server.Close()
+
+	testClient := mockedClient(t, server)
+	testClient.Config.RelayURL = mockRelayServer.URL
+
+	relayClient, err := testClient.OutboundRelayClient(evervault.WithFetchedDecryptionDomains())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	relayedRequests(t, relayClient, []string{"http://api.relayed.com/", "http://relayed.net/", server.URL})
+
+	assert.Equal(t, []string{"api.relayed.com", "relayed.net"}, relayedHosts)
+}
+
+func TestOutboundRelayClientCachesCA(t *testing.T) {
+	t.Parallel()
+
+	mockServer := startMockHTTPServer("", "")
+	defer mockServer.Close()
+
+	var caRequests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ca.crt" {
+			caRequests.Add(1)
+		}
+
+		mockServer.Config.Handler.ServeHTTP(writer, r)
+	}))
+	defer server.Close()
+
+	testClient := mockedClient(t, server)
+
+	for i := 0; i < 2; i++ {
+		_, err := testClient.OutboundRelayClient()
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(1), caRequests.Load())
+}
+
+func TestOutboundRelayClientRejectsInvalidCA(t *testing.T) {
+	t.Parallel()
+
+	server := startMockHTTPServer("", "")
+	defer server.Close()
+
+	testClient := mockedClient(t, server)
+	testClient.Config.EvervaultCaURL = server.URL + "/cages/key"
+
+	_, err := testClient.OutboundRelayClient()
+	assert.ErrorIs(t, err, evervault.ErrInvalidCACertificate)
+
+	caPath := filepath.Join(t.TempDir(), "ca.crt")
+	if err := os.WriteFile(caPath, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	testClient.Config.EvervaultCaPath = caPath
+
+	_, err = testClient.OutboundRelayClient()
+	assert.ErrorIs(t, err, evervault.ErrInvalidCACertificate)
+}
+
+func TestOutboundRelayClientCAFile(t *testing.T) {
+	t.Parallel()
+
+	mockServer := startMockHTTPServer("", "")
+	defer mockServer.Close()
+
+	caPath := filepath.Join(t.TempDir(), "ca.crt")
+	if err := os.WriteFile(caPath, testCACert(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		assert.NotEqual(t, "/ca.crt", r.URL.Path)
+		mockServer.Config.Handler.ServeHTTP(writer, r)
+	}))
+	defer server.Close()
+
+	testClient, err := evervault.New("test_app_uuid", "test_api_key",
+		evervault.WithAPIURL(server.URL),
+		evervault.WithCAURL(server.URL+"/ca.crt"),
+		evervault.WithCAFile(caPath),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = testClient.OutboundRelayClient()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,68 @@
+package evervault
+
+import (
+	"context"
+
+	"github.com/evervault/evervault-go/internal/telemetry"
+)
+
+// Operations reported to Instrumentation.
+const (
+	// OperationEncrypt is reported for every encryption, with the datatype attribute.
+	OperationEncrypt = "evervault.encrypt"
+	// OperationDecrypt is reported for every call to the /decrypt endpoint.
+	OperationDecrypt = "evervault.decrypt"
+	// OperationFunctionRun is reported for every Function run, with the function attribute.
+	OperationFunctionRun = "evervault.function.run"
+	// OperationAPIRequest is reported for every request to the Evervault API, with the method, path and
+	// status_code attributes.
+	OperationAPIRequest = "evervault.api.request"
+	// OperationEnclaveDial is reported for every connection dialed to an enclave, with the hostname attribute.
+	// Connections that fail attestation end with ErrAttestionFailure.
+	OperationEnclaveDial = "evervault.enclave.dial"
+	// OperationAttestationDocFetch is reported for every attestation doc fetched from an enclave, with the
+	// hostname and attempts attributes.
+	OperationAttestationDocFetch = telemetry.OperationAttestationDocFetch
+	// OperationPCRRefresh is reported every time the PCRs provider is polled, with the hostname attribute.
+	OperationPCRRefresh = telemetry.OperationPCRRefresh
+)
+
+// Instrumentation is notified of every operation performed by the Client so traces and metrics can be
+// recorded alongside the rest of your stack. Set it on Config.Instrumentation.
+//
+// Start is called when an operation begins and End is called on the returned Span once it completes,
+// so the time between the two is the latency of the operation. An OpenTelemetry adapter looks like:
+//
+//	type otelInstrumentation struct{ tracer trace.Tracer }
+//
+//	func (o otelInstrumentation) Start(
+//		ctx context.Context, op string, attrs map[string]string,
+//	) (context.Context, evervault.Span) {
+//		ctx, span := o.tracer.Start(ctx, op)
+//		for key, value := range attrs {
+//			span.SetAttributes(attribute.String(key, value))
+//		}
+//		return ctx, otelSpan{span}
+//	}
+type Instrumentation interface {
+	Start(ctx context.Context, operation string, attributes map[string]string) (context.Context, Span)
+}
+
+// Span records the outcome of a single operation reported to Instrumentation.
+type Span interface {
+	SetAttribute(key, value string)
+	// End is called once the operation completes, with the error it failed with or nil.
+	End(err error)
+}
+
+// tracer returns the configured Instrumentation as a StartFunc for the internal packages.
+func (c *Client) tracer() telemetry.StartFunc {
+	instrumentation := c.Config.Instrumentation
+	if instrumentation == nil {
+		return nil
+	}
+
+	return func(ctx context.Context, operation string, attributes map[string]string) (context.Context, telemetry.Span) {
+		return instrumentation.Start(ctx, operation, attributes)
+	}
+}
@@ -0,0 +1,115 @@
+//go:build unit_test
+// +build unit_test
+
+package evervault_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/evervault/evervault-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedSpan struct {
+	operation  string
+	attributes map[string]string
+	ended      bool
+	err        error
+}
+
+func (s *recordedSpan) SetAttribute(key, value string) {
+	s.attributes[key] = value
+}
+
+func (s *recordedSpan) End(err error) {
+	s.ended = true
+	s.err = err
+}
+
+type recordingInstrumentation struct {
+	mutex sync.Mutex
+	spans []*recordedSpan
+}
+
+func (r *recordingInstrumentation) Start(
+	ctx context.Context,
+	operation string,
+	attributes map[string]string,
+) (context.Context, evervault.Span) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	span := &recordedSpan{operation: operation, attributes: map[string]string{}}
+	for key, value := range attributes {
+		span.attributes[key] = value
+	}
+
+	r.spans = append(r.spans, span)
+
+	return ctx, span
+}
+
+func (r *recordingInstrumentation) find(operation string) []*recordedSpan {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var spans []*recordedSpan
+
+	for _, span := range r.spans {
+		if span.operation == operation {
+			spans = append(spans, span)
+		}
+	}
+
+	return spans
+}
+
+func TestInstrumentationRecordsOperations(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+
+	server := startMockHTTPServer("decrypted", "")
+	defer server.Close()
+
+	instrumentation := &recordingInstrumentation{}
+
+	config := evervault.Config{
+		EvAPIURL:        server.URL,
+		Instrumentation: instrumentation,
+	}
+
+	testClient, err := evervault.MakeCustomClient("test_api_key", "test_app_uuid", config)
+	if err != nil {
+		t.Errorf("Error creating evervault client: %s", err)
+		return
+	}
+
+	_, err = testClient.EncryptInt(100)
+	assert.Nil(err)
+
+	_, err = testClient.DecryptString("ev:abc123")
+	assert.Nil(err)
+
+	encrypts := instrumentation.find(evervault.OperationEncrypt)
+	if assert.Len(encrypts, 1) {
+		assert.Equal("number", encrypts[0].attributes["datatype"])
+		assert.True(encrypts[0].ended)
+		assert.Nil(encrypts[0].err)
+	}
+
+	decrypts := instrumentation.find(evervault.OperationDecrypt)
+	if assert.Len(decrypts, 1) {
+		assert.True(decrypts[0].ended)
+	}
+
+	requests := instrumentation.find(evervault.OperationAPIRequest)
+	if assert.Len(requests, 2) {
+		assert.Equal("/cages/key", requests[0].attributes["path"])
+		assert.Equal("/decrypt", requests[1].attributes["path"])
+		assert.Equal("POST", requests[1].attributes["method"])
+		assert.Equal("200", requests[1].attributes["status_code"])
+	}
+}
@@ -0,0 +1,182 @@
+package evervault
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// TokenActionDecrypt is the action of client-side tokens that decrypt their payloads.
+	TokenActionDecrypt = "api:decrypt"
+	// maxTokenExpiry is the longest a client-side token can be valid for.
+	maxTokenExpiry = 10 * time.Minute
+)
+
+// CreateTokenOptions describes a client-side token created with CreateToken.
+type CreateTokenOptions struct {
+	// Action the token can perform, TokenActionDecrypt if empty.
+	Action string
+	// Payloads the token can be used with, at least one is required. With more than one payload the token can be
+	// used with them together as a JSON array, in the same order.
+	Payloads []any
+	// Expiry is the time the token expires, at most 10 minutes from now. Evervault defaults to 5 minutes if it is
+	// zero.
+	Expiry time.Time
+}
+
+// Token is a client-side token created with CreateToken.
+type Token struct {
+	Value     string    // Value of the token, passed to the Evervault API by the client.
+	ExpiresAt time.Time // Time the token expires.
+}
+
+// CreateToken creates a time bound client-side token that can only be used to perform its action on its payloads.
+//
+//	token, err := evClient.CreateToken(evervault.CreateTokenOptions{
+//		Payloads: []any{encryptedCard},
+//		Expiry:   time.Now().Add(time.Minute),
+//	})
+//
+// If there are no payloads or a payload is nil ErrInvalidDataType is returned. If the expiry is not in the future
+// or more than 10 minutes from now an error wrapping ErrInvalidTokenExpiry is returned.
+func (c *Client) CreateToken(options CreateTokenOptions) (Token, error) {
+	if len(options.Payloads) == 0 {
+		return Token{}, ErrInvalidDataType
+	}
+
+	for _, payload := range options.Payloads {
+		if payload == nil {
+			return Token{}, ErrInvalidDataType
+		}
+	}
+
+	var expiry int64
+
+	if !options.Expiry.IsZero() {
+		now := time.Now()
+		if !options.Expiry.After(now) {
+			return Token{}, ErrInvalidTokenExpiry
+		}
+
+		if options.Expiry.After(now.Add(maxTokenExpiry)) {
+			return Token{}, fmt.Errorf("%w: client-side tokens expire at most %s from now", ErrInvalidTokenExpiry,
+				maxTokenExpiry)
+		}
+
+		expiry = options.Expiry.UnixMilli()
+	}
+
+	action := options.Action
+	if action == "" {
+		action = TokenActionDecrypt
+	}
+
+	var payload any = options.Payloads
+	if len(options.Payloads) == 1 {
+		payload = options.Payloads[0]
+	}
+
+	response, err := c.createToken(action, payload, expiry)
+	if err != nil {
+		return Token{}, err
+	}
+
+	return Token{Value: response.Token, ExpiresAt: time.UnixMilli(response.Expiry)}, nil
+}
+
+// TokenClient decrypts data with a client-side token created by CreateToken or CreateClientSideDecryptToken
+// instead of the App API key, for services that should not hold the App credentials.
+//
+//	tokenClient, err := evervault.NewTokenClient(token.Value)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	decrypted, err := tokenClient.DecryptString(encrypted)
+//
+// A token can only decrypt the payload it was created for, so every call must pass that payload.
+type TokenClient struct {
+	client *Client
+}
+
+// NewTokenClient creates a TokenClient that authenticates to the Evervault API with a client-side token. The
+// Evervault defaults are used unless overridden with an Option.
+//
+// If the token is empty or an Option is malformed an error wrapping ErrInvalidConfig is returned.
+func NewTokenClient(token string, opts ...Option) (*TokenClient, error) {
+	if token == "" {
+		return nil, fmt.Errorf("%w: a client-side token is required", ErrInvalidConfig)
+	}
+
+	config := defaultConfig()
+
+	for _, opt := range opts {
+		if err := opt(&config); err != nil {
+			return nil, err
+		}
+	}
+
+	config = config.withDefaults()
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &TokenClient{client: &Client{Config: config, token: token}}, nil
+}
+
+// Decrypt decrypts every encrypted string in a payload, such as the JSON array of the payloads of a token created
+// with several. Strings, numbers and booleans are returned as decoded from JSON.
+//
+// If the token has expired or was not created for the payload an error wrapping ErrTokenRejected is returned.
+func (t *TokenClient) Decrypt(payload any) (any, error) {
+	decrypted, err := t.client.decrypt(payload)
+
+	return decrypted, tokenError(err)
+}
+
+// DecryptString decrypts a string previously encrypted with Encrypt or through Relay.
+//
+// If the token has expired or was not created for the encrypted data an error wrapping ErrTokenRejected is returned.
+func (t *TokenClient) DecryptString(encryptedData string) (string, error) {
+	decrypted, err := t.client.DecryptString(encryptedData)
+
+	return decrypted, tokenError(err)
+}
+
+// DecryptInt decrypts an int previously encrypted with Encrypt or through Relay.
+//
+// If the token has expired or was not created for the encrypted data an error wrapping ErrTokenRejected is returned.
+func (t *TokenClient) DecryptInt(encryptedData string) (int, error) {
+	decrypted, err := t.client.DecryptInt(encryptedData)
+
+	return decrypted, tokenError(err)
+}
+
+// DecryptFloat64 decrypts a float64 previously encrypted with Encrypt or through Relay.
+//
+// If the token has expired or was not created for the encrypted data an error wrapping ErrTokenRejected is returned.
+func (t *TokenClient) DecryptFloat64(encryptedData string) (float64, error) {
+	decrypted, err := t.client.DecryptFloat64(encryptedData)
+
+	return decrypted, tokenError(err)
+}
+
+// DecryptBool decrypts a bool previously encrypted with Encrypt or through Relay.
+//
+// If the token has expired or was not created for the encrypted data an error wrapping ErrTokenRejected is returned.
+func (t *TokenClient) DecryptBool(encryptedData string) (bool, error) {
+	decrypted, err := t.client.DecryptBool(encryptedData)
+
+	return decrypted, tokenError(err)
+}
+
+// tokenError wraps errors of requests the Evervault API rejected the client-side token of with ErrTokenRejected,
+// keeping the APIError.
+func tokenError(err error) error {
+	if errors.Is(err, ErrForbidden) || errors.Is(err, ErrUnauthorized) {
+		return fmt.Errorf("%w: %w", ErrTokenRejected, err)
+	}
+
+	return err
+}
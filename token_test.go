@@ -0,0 +1,222 @@
+//go:build unit_test
+// +build unit_test
+
+package evervault_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/evervault/evervault-go"
+	"github.com/evervault/evervault-go/evervaulttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateToken(t *testing.T) {
+	t.Parallel()
+
+	server := evervaulttest.NewServer()
+	defer server.Close()
+
+	client, err := server.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := client.CreateToken(evervault.CreateTokenOptions{Payloads: []any{"ev:encrypted"}})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token.Value)
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), token.ExpiresAt, time.Minute)
+
+	expiry := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+
+	token, err = client.CreateToken(evervault.CreateTokenOptions{Payloads: []any{"ev:encrypted"}, Expiry: expiry})
+	assert.NoError(t, err)
+	assert.True(t, expiry.Equal(token.ExpiresAt))
+}
+
+func TestCreateTokenSendsActionAndPayloads(t *testing.T) {
+	t.Parallel()
+
+	mockServer := startMockHTTPServer(nil, "")
+	defer mockServer.Close()
+
+	var body map[string]any
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/client-side-tokens" {
+			mockServer.Config.Handler.ServeHTTP(writer, r)
+			return
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		writer.Write([]byte(`{"token": "test_token", "expiry": 1700000000000}`))
+	}))
+	defer server.Close()
+
+	client := mockedClient(t, server)
+
+	token, err := client.CreateToken(evervault.CreateTokenOptions{
+		Action:   "api:inspect",
+		Payloads: []any{"ev:first", map[string]any{"card": "ev:second"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "test_token", token.Value)
+	assert.Equal(t, time.UnixMilli(1700000000000), token.ExpiresAt)
+	assert.Equal(t, "api:inspect", body["action"])
+	assert.Equal(t, []any{"ev:first", map[string]any{"card": "ev:second"}}, body["payload"])
+	assert.Equal(t, float64(0), body["expiry"])
+
+	_, err = client.CreateToken(evervault.CreateTokenOptions{Payloads: []any{"ev:first"}})
+	assert.NoError(t, err)
+	assert.Equal(t, evervault.TokenActionDecrypt, body["action"])
+	assert.Equal(t, "ev:first", body["payload"])
+}
+
+func TestCreateTokenValidatesOptions(t *testing.T) {
+	t.Parallel()
+
+	server := startMockHTTPServer(nil, "")
+	defer server.Close()
+
+	client := mockedClient(t, server)
+
+	_, err := client.CreateToken(evervault.CreateTokenOptions{})
+	assert.ErrorIs(t, err, evervault.ErrInvalidDataType)
+
+	_, err = client.CreateToken(evervault.CreateTokenOptions{Payloads: []any{"ev:first", nil}})
+	assert.ErrorIs(t, err, evervault.ErrInvalidDataType)
+
+	_, err = client.CreateToken(evervault.CreateTokenOptions{
+		Payloads: []any{"ev:first"},
+		Expiry:   time.Now().Add(-time.Minute),
+	})
+	assert.ErrorIs(t, err, evervault.ErrInvalidTokenExpiry)
+
+	_, err = client.CreateToken(evervault.CreateTokenOptions{
+		Payloads: []any{"ev:first"},
+		Expiry:   time.Now().Add(11 * time.Minute),
+	})
+	assert.ErrorIs(t, err, evervault.ErrInvalidTokenExpiry)
+}
+
+func startTokenServer(t *testing.T) (*evervaulttest.Server, *evervault.Client) {
+	t.Helper()
+
+	server := evervaulttest.NewServer()
+	t.Cleanup(server.Close)
+
+	client, err := server.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return server, client
+}
+
+func TestTokenClientDecrypts(t *testing.T) {
+	t.Parallel()
+
+	server, client := startTokenServer(t)
+
+	encrypted, err := client.EncryptString("plaintext")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encryptedNumber, err := client.EncryptInt(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := client.CreateToken(evervault.CreateTokenOptions{Payloads: []any{encrypted}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenClient, err := evervault.NewTokenClient(token.Value, evervault.WithAPIURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, err := tokenClient.DecryptString(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "plaintext", decrypted)
+
+	token, err = client.CreateToken(evervault.CreateTokenOptions{Payloads: []any{encrypted, encryptedNumber}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenClient, err = evervault.NewTokenClient(token.Value, evervault.WithAPIURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batch, err := tokenClient.Decrypt([]any{encrypted, encryptedNumber})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"plaintext", float64(42)}, batch)
+}
+
+func TestTokenClientRejectedToken(t *testing.T) {
+	t.Parallel()
+
+	server, client := startTokenServer(t)
+
+	encrypted, err := client.EncryptString("plaintext")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := client.EncryptString("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := client.CreateToken(evervault.CreateTokenOptions{
+		Payloads: []any{encrypted},
+		Expiry:   time.Now().Add(100 * time.Millisecond),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenClient, err := evervault.NewTokenClient(token.Value, evervault.WithAPIURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = tokenClient.DecryptString(other)
+	assert.ErrorIs(t, err, evervault.ErrTokenRejected)
+	assert.ErrorIs(t, err, evervault.ErrForbidden)
+
+	time.Sleep(time.Until(token.ExpiresAt) + 10*time.Millisecond)
+
+	_, err = tokenClient.DecryptString(encrypted)
+	assert.ErrorIs(t, err, evervault.ErrTokenRejected)
+
+	tokenClient, err = evervault.NewTokenClient("ev_token_unknown", evervault.WithAPIURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = tokenClient.DecryptString(encrypted)
+	assert.ErrorIs(t, err, evervault.ErrTokenRejected)
+}
+
+func TestNewTokenClientRequiresToken(t *testing.T) {
+	t.Parallel()
+
+	_, err := evervault.NewTokenClient("")
+	assert.ErrorIs(t, err, evervault.ErrInvalidConfig)
+
+	_, err = evervault.NewTokenClient("ev_token_1", evervault.WithAPIURL("not a url"))
+	assert.ErrorIs(t, err, evervault.ErrInvalidConfig)
+}